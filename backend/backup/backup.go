@@ -0,0 +1,376 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/storage"
+	"gorm.io/gorm"
+)
+
+// FormatVersion is the version of the archive layout written by Create
+const FormatVersion = 1
+
+// Entries of a backup archive. Upload files are stored under UploadsPrefix followed by their storage key.
+const (
+	ManifestName  = "manifest.json"
+	DatabaseName  = "database.db"
+	UploadsPrefix = "uploads/"
+)
+
+// ArchiveExtension is appended to the names of archives written by WriteToDir
+const ArchiveExtension = ".tar.gz"
+
+// archivePrefix starts the names of archives written by WriteToDir, which Prune relies on
+const archivePrefix = "tower_tracker-"
+
+// FileEntry describes one file of a backup archive
+type FileEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists the contents of a backup archive. It is the last entry of the archive.
+type Manifest struct {
+	FormatVersion int         `json:"format_version"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Driver        string      `json:"driver"`
+	SchemaVersion int         `json:"schema_version"` // Latest applied migration
+	Files         []FileEntry `json:"files"`
+}
+
+// Create writes a gzipped tar archive holding a consistent snapshot of the SQLite database, every
+// upload file and a manifest with their checksums. The database is copied with VACUUM INTO, so the
+// server can keep running. Files deleted from storage while the archive is written are skipped.
+func Create(ctx context.Context, db *gorm.DB, store storage.Storage, w io.Writer) (*Manifest, error) {
+	if database.Driver != database.DriverSQLite {
+		return nil, errors.New("backups only support SQLite, use pg_dump for PostgreSQL")
+	}
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Driver:        database.Driver,
+	}
+	if err := db.Model(&database.SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&manifest.SchemaVersion).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "tower-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	snapshot := filepath.Join(tmpDir, DatabaseName)
+	if err := db.Exec("VACUUM INTO ?", snapshot).Error; err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	file, err := os.Open(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err == nil {
+		err = addFile(archive, manifest, DatabaseName, file, info.Size(), info.ModTime())
+	}
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored files: %w", err)
+	}
+	for _, object := range objects {
+		reader, err := store.Get(ctx, object.Key)
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Backup: skipping %s, deleted while the backup was running", object.Key)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", object.Key, err)
+		}
+		err = addFile(archive, manifest, UploadsPrefix+object.Key, reader, object.Size, object.ModTime)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	header := &tar.Header{Name: ManifestName, Mode: 0o644, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err := archive.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	if _, err := archive.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// addFile copies r into the archive and records its checksum in the manifest
+func addFile(archive *tar.Writer, manifest *Manifest, name string, r io.Reader, size int64, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: modTime}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	written, err := io.Copy(archive, io.TeeReader(r, hash))
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", name, err)
+	}
+	if written != size {
+		return fmt.Errorf("failed to archive %s: size changed from %d to %d bytes", name, size, written)
+	}
+
+	manifest.Files = append(manifest.Files, FileEntry{Path: name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))})
+	return nil
+}
+
+// WriteToDir creates a timestamped archive in dir and returns its path. The archive is written
+// under a temporary name first, so that an interrupted backup never looks complete.
+func WriteToDir(ctx context.Context, db *gorm.DB, store storage.Storage, dir string) (string, *Manifest, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", nil, err
+	}
+
+	name := filepath.Join(dir, archivePrefix+time.Now().Format("20060102-150405")+ArchiveExtension)
+	if _, err := os.Stat(name); err == nil {
+		return "", nil, fmt.Errorf("%s already exists", name)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".backup-*")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	manifest, err := Create(ctx, db, store, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return "", nil, err
+	}
+	return name, manifest, nil
+}
+
+// Prune deletes all but the newest keep archives written by WriteToDir to dir and returns their paths
+func Prune(dir string, keep int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), archivePrefix) && strings.HasSuffix(entry.Name(), ArchiveExtension) {
+			archives = append(archives, entry.Name())
+		}
+	}
+	// The timestamp in the name sorts chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(archives)))
+
+	var removed []string
+	for i := keep; i < len(archives); i++ {
+		name := filepath.Join(dir, archives[i])
+		if err := os.Remove(name); err != nil {
+			return removed, err
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
+
+// Extract unpacks an archive into dir and verifies every file against the manifest: all listed files
+// must be present with the recorded size and SHA-256, and no other files may be present.
+func Extract(r io.Reader, dir string) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	archive := tar.NewReader(gz)
+
+	var manifest *Manifest
+	found := make(map[string]FileEntry)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("corrupt backup archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if header.Name == ManifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(archive).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			continue
+		}
+
+		name, ok := cleanEntryName(header.Name)
+		if !ok {
+			return nil, fmt.Errorf("invalid file name %q in backup archive", header.Name)
+		}
+		entry, err := extractFile(archive, filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+		entry.Path = name
+		found[name] = entry
+	}
+
+	if manifest == nil {
+		return nil, errors.New("backup archive has no manifest")
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+	for _, expected := range manifest.Files {
+		actual, ok := found[expected.Path]
+		if !ok {
+			return nil, fmt.Errorf("%s is listed in the manifest but missing from the archive", expected.Path)
+		}
+		if actual.Size != expected.Size || actual.SHA256 != expected.SHA256 {
+			return nil, fmt.Errorf("checksum mismatch for %s", expected.Path)
+		}
+		delete(found, expected.Path)
+	}
+	for name := range found {
+		return nil, fmt.Errorf("%s is not listed in the manifest", name)
+	}
+	return manifest, nil
+}
+
+// cleanEntryName rejects archive entries that would be extracted outside the target directory
+func cleanEntryName(name string) (string, bool) {
+	cleaned := path.Clean(name)
+	if path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+	if cleaned != DatabaseName && !strings.HasPrefix(cleaned, UploadsPrefix) {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// extractFile writes r to target and returns its size and checksum
+func extractFile(r io.Reader, target string) (FileEntry, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return FileEntry{}, err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(file, io.TeeReader(r, hash))
+	if err != nil {
+		return FileEntry{}, err
+	}
+	return FileEntry{Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, file.Close()
+}
+
+// Restore replaces the SQLite database at dbPath and puts every upload file back into store from a
+// verified archive that Extract unpacked into dir. The server must not be running. The current
+// database is kept next to dbPath with a ".pre-restore" suffix; upload files missing from the
+// archive are left in place for the upload reconciler to report.
+func Restore(ctx context.Context, dir string, manifest *Manifest, dbPath string, store storage.Storage) error {
+	if _, err := os.Stat(dbPath); err == nil {
+		if err := os.Rename(dbPath, dbPath+".pre-restore"); err != nil {
+			return fmt.Errorf("failed to keep the current database: %w", err)
+		}
+	}
+	// Stale journal files would otherwise be applied to the restored database
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		os.Remove(dbPath + suffix)
+	}
+	if err := copyFile(filepath.Join(dir, DatabaseName), dbPath); err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	for _, entry := range manifest.Files {
+		if !strings.HasPrefix(entry.Path, UploadsPrefix) {
+			continue
+		}
+		key := strings.TrimPrefix(entry.Path, UploadsPrefix)
+		file, err := os.Open(filepath.Join(dir, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return err
+		}
+		err = store.Put(ctx, key, file, entry.Size, contentType(key))
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(to, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// contentType guesses the content type of a restored upload from its key
+func contentType(key string) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/user/tower-tracker-bima/backend/backup"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/storage"
+)
+
+// Backup implements the "backup" subcommand, which writes an archive of the database, the upload
+// files and a manifest with their checksums while the server keeps running.
+func Backup(args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	dir := flags.String("dir", "backups", "directory to write the timestamped archive to")
+	out := flags.String("out", "", "path of the archive, instead of a timestamped name in --dir")
+	keep := flags.Int("keep", 0, "delete all but this many of the newest archives in --dir (0 keeps all)")
+	flags.Parse(args)
+
+	if err := InitDatabase(false); err != nil {
+		return err
+	}
+	storage.InitStorage()
+	ctx := context.Background()
+
+	var path string
+	var manifest *backup.Manifest
+	if *out != "" {
+		if _, err := os.Stat(*out); err == nil {
+			return fmt.Errorf("%s already exists", *out)
+		}
+		if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+			return err
+		}
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		manifest, err = backup.Create(ctx, database.DB, storage.Default, file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(*out)
+			return err
+		}
+		path = *out
+	} else {
+		var err error
+		if path, manifest, err = backup.WriteToDir(ctx, database.DB, storage.Default, *dir); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Backed up the database and %d upload file(s) to %s.\n", len(manifest.Files)-1, path)
+
+	if *keep > 0 {
+		removed, err := backup.Prune(*dir, *keep)
+		if err != nil {
+			return err
+		}
+		for _, name := range removed {
+			fmt.Printf("Removed old backup %s.\n", name)
+		}
+	}
+	return nil
+}
+
+// Restore implements the "restore" subcommand, which verifies a backup archive against its manifest
+// and then restores the SQLite database and the upload files. Stop the server before restoring.
+func Restore(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	file := flags.String("file", "", "backup archive to restore (required)")
+	verifyOnly := flags.Bool("verify-only", false, "only verify the archive's checksums")
+	flags.Parse(args)
+
+	if *file == "" {
+		return errors.New("--file is required")
+	}
+
+	src, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dir, err := os.MkdirTemp("", "tower-restore-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	manifest, err := backup.Extract(src, dir)
+	if err != nil {
+		return fmt.Errorf("%s failed verification: %w", *file, err)
+	}
+	fmt.Printf("Verified %d file(s) of the backup from %s (schema version %d).\n",
+		len(manifest.Files), manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"), manifest.SchemaVersion)
+	if *verifyOnly {
+		return nil
+	}
+
+	dbPath, ok := database.SQLitePath()
+	if !ok {
+		return errors.New("restore only supports SQLite, use pg_restore for PostgreSQL")
+	}
+	storage.InitStorage()
+	if err := backup.Restore(context.Background(), dir, manifest, dbPath, storage.Default); err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored the database to %s and %d upload file(s).\n", dbPath, len(manifest.Files)-1)
+	fmt.Println("Run \"migrate up\" if the backup predates the current schema.")
+	return nil
+}
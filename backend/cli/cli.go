@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/models"
+)
+
+// InitDatabase connects to the database and verifies that its schema is current, applying
+// pending migrations first when autoMigrate is set. Every command except "migrate" uses it,
+// so that no command runs against an outdated schema.
+func InitDatabase(autoMigrate bool) error {
+	database.ConnectDatabase()
+	return database.EnsureSchemaCurrent(database.DB, autoMigrate)
+}
+
+// WarnIfNoUsers logs how to create the first account when the database has no users
+func WarnIfNoUsers() {
+	var count int64
+	database.DB.Model(&models.User{}).Count(&count)
+	if count == 0 {
+		log.Println("No users exist yet. Create an administrator with: create-admin --email <email>")
+	}
+}
+
+// generatePassword returns a random password of at least minLength characters for accounts created
+// or reset without one
+func generatePassword(minLength int) (string, error) {
+	buf := make([]byte, max(12, (minLength*3+3)/4))
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// GenerateJWTKey implements the "generate-jwt-key" subcommand, which writes a new private key for
+// signing tokens in PKCS #8 PEM format. Point JWT_SIGNING_KEY_FILE at it, and move the file of the
+// previous key to JWT_PREVIOUS_KEY_FILES until JWT_TTL has passed.
+func GenerateJWTKey(args []string) error {
+	flags := flag.NewFlagSet("generate-jwt-key", flag.ExitOnError)
+	keyType := flags.String("type", "ed25519", "key type: ed25519 (EdDSA) or rsa (RS256)")
+	bits := flags.Int("bits", 3072, "size of RSA keys")
+	out := flags.String("out", "", "file to write the key to (required)")
+	flags.Parse(args)
+
+	if *out == "" {
+		return errors.New("--out is required")
+	}
+
+	var key interface{}
+	var err error
+	switch *keyType {
+	case "ed25519":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case "rsa":
+		if *bits < 2048 {
+			return errors.New("--bits must be at least 2048")
+		}
+		key, err = rsa.GenerateKey(rand.Reader, *bits)
+	default:
+		return fmt.Errorf("unknown key type %q, use ed25519 or rsa", *keyType)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	// O_EXCL keeps an existing key from being overwritten by accident
+	file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s key to %s.\n", *keyType, *out)
+	return nil
+}
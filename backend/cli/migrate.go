@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/user/tower-tracker-bima/backend/database"
+)
+
+// Migrate implements the "migrate up|down|status" subcommand
+func Migrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down|status")
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations to roll back (down only)")
+	flags.Parse(args[1:])
+
+	database.ConnectDatabase()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(database.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s).\n", len(applied))
+	case "down":
+		if *steps < 1 {
+			return errors.New("-steps must be at least 1")
+		}
+		rolledBack, err := database.MigrateDown(database.DB, *steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migration(s).\n", len(rolledBack))
+	case "status":
+		statuses, err := database.MigrationStatuses(database.DB)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-30s  %s\n", status.Version, status.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", args[0])
+	}
+	return nil
+}
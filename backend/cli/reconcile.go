@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/jobs"
+	"github.com/user/tower-tracker-bima/backend/storage"
+)
+
+// ReconcileUploads implements the "reconcile-uploads" subcommand, which reports orphaned upload
+// files and missing tower photos and optionally quarantines or deletes the orphans. Unless it only
+// reports, it also purges files that have been in quarantine longer than the retention period.
+func ReconcileUploads(args []string) error {
+	flags := flag.NewFlagSet("reconcile-uploads", flag.ExitOnError)
+	action := flags.String("action", jobs.ActionReport, "what to do with orphaned files: report, quarantine or delete")
+	grace := flags.Duration("grace", jobs.DefaultReconcileGracePeriod, "minimum age of a file before it counts as orphaned")
+	retention := flags.Duration("quarantine-retention", jobs.DefaultQuarantineRetention,
+		"how long quarantined files are kept before quarantine and delete runs purge them (0 keeps them forever)")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Parse(args)
+
+	if err := InitDatabase(false); err != nil {
+		return err
+	}
+	storage.InitStorage()
+
+	report, err := jobs.ReconcileUploads(context.Background(), database.DB, storage.Default, jobs.ReconcileOptions{
+		Action:              *action,
+		GracePeriod:         *grace,
+		QuarantineRetention: *retention,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	fmt.Printf("Orphaned files (%d):\n", len(report.OrphanedFiles))
+	for _, object := range report.OrphanedFiles {
+		fmt.Printf("  %s\t%d bytes\t%s\n", object.Key, object.Size, object.ModTime.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Printf("Missing photos (%d):\n", len(report.MissingPhotos))
+	for _, missing := range report.MissingPhotos {
+		fmt.Printf("  tower %d\tphoto %d\t%s\n", missing.TowerID, missing.PhotoID, missing.URL)
+	}
+	fmt.Printf("Skipped %d unreferenced files younger than %s.\n", report.SkippedRecent, *grace)
+	if report.Action != jobs.ActionReport {
+		fmt.Printf("Orphaned files processed (%s): %d\n", report.Action, report.Processed)
+		if *retention > 0 {
+			fmt.Printf("Quarantined files older than %s purged: %d\n", *retention, report.Purged)
+		}
+	}
+	return nil
+}
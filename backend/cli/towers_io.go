@@ -0,0 +1,341 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"gorm.io/gorm"
+)
+
+// towerRecord is the import/export representation of a tower, with providers referenced by name
+type towerRecord struct {
+	ID        uint     `json:"id,omitempty"`
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Kelurahan string   `json:"kelurahan"`
+	Kecamatan string   `json:"kecamatan"`
+	Address   string   `json:"address"`
+	Tinggi    float64  `json:"tinggi"`
+	Tipe      string   `json:"tipe"`
+	Status    string   `json:"status"`
+	PhotoURL  string   `json:"photo_url,omitempty"`
+	Providers []string `json:"providers"`
+}
+
+// towerCSVHeader is the column order of exported tower CSV files. Imports match columns by name,
+// and multiple providers are separated by semicolons.
+var towerCSVHeader = []string{"id", "latitude", "longitude", "kelurahan", "kecamatan", "address", "tinggi", "tipe", "status", "photo_url", "providers"}
+
+func (r towerRecord) csvRow() []string {
+	return []string{
+		strconv.FormatUint(uint64(r.ID), 10),
+		strconv.FormatFloat(r.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(r.Longitude, 'f', -1, 64),
+		r.Kelurahan,
+		r.Kecamatan,
+		r.Address,
+		strconv.FormatFloat(r.Tinggi, 'f', -1, 64),
+		r.Tipe,
+		r.Status,
+		r.PhotoURL,
+		strings.Join(r.Providers, ";"),
+	}
+}
+
+// ImportTowers implements the "import-towers" subcommand, which creates towers from a CSV or JSON
+// file in the format written by "export". IDs and photo URLs in the file are ignored.
+func ImportTowers(args []string) error {
+	flags := flag.NewFlagSet("import-towers", flag.ExitOnError)
+	file := flags.String("file", "", "CSV or JSON file to import (required)")
+	format := flags.String("format", "", "csv or json (default: from the file extension)")
+	createProviders := flags.Bool("create-providers", false, "create providers that do not exist yet instead of failing")
+	dryRun := flags.Bool("dry-run", false, "validate the file without saving anything")
+	flags.Parse(args)
+
+	if *file == "" {
+		return errors.New("--file is required")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+
+	src, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	var records []towerRecord
+	switch *format {
+	case "csv":
+		records, err = readTowerCSV(src)
+	case "json":
+		err = json.NewDecoder(src).Decode(&records)
+	default:
+		return fmt.Errorf("unsupported format %q, expected csv or json", *format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", *file, err)
+	}
+
+	if err := InitDatabase(false); err != nil {
+		return err
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		providers := make(map[string]*models.Provider)
+		for i, record := range records {
+			tower, err := towerFromRecord(tx, record, providers, *createProviders)
+			if err != nil {
+				return fmt.Errorf("record %d: %w", i+1, err)
+			}
+			if err := tx.Create(tower).Error; err != nil {
+				return fmt.Errorf("record %d: failed to create tower: %w", i+1, err)
+			}
+
+			newData, _ := json.Marshal(record)
+			event := models.TowerEvent{
+				TowerID:     tower.ID,
+				EventType:   "Created",
+				Timestamp:   time.Now(),
+				Description: "Tower imported from " + filepath.Base(*file) + ".",
+				OldData:     "null",
+				NewData:     string(newData),
+			}
+			if err := tx.Create(&event).Error; err != nil {
+				return fmt.Errorf("record %d: failed to create tower event: %w", i+1, err)
+			}
+		}
+
+		if *dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		fmt.Printf("Dry run: %d tower(s) would be imported.\n", len(records))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d tower(s).\n", len(records))
+	return nil
+}
+
+// errDryRun rolls back the import transaction of a dry run
+var errDryRun = errors.New("dry run")
+
+// towerFromRecord builds a tower from an import record, resolving its providers by name
+func towerFromRecord(tx *gorm.DB, record towerRecord, providers map[string]*models.Provider, createProviders bool) (*models.Tower, error) {
+	if record.Latitude < -90 || record.Latitude > 90 || record.Longitude < -180 || record.Longitude > 180 {
+		return nil, fmt.Errorf("invalid coordinates (%f, %f)", record.Latitude, record.Longitude)
+	}
+	if record.Status == "" {
+		record.Status = "active"
+	}
+
+	tower := &models.Tower{
+		Latitude:  record.Latitude,
+		Longitude: record.Longitude,
+		Kelurahan: record.Kelurahan,
+		Kecamatan: record.Kecamatan,
+		Address:   record.Address,
+		Tinggi:    record.Tinggi,
+		Tipe:      record.Tipe,
+		Status:    record.Status,
+	}
+
+	for _, name := range record.Providers {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		provider, ok := providers[name]
+		if !ok {
+			provider = &models.Provider{}
+			if err := tx.Where("name = ?", name).Limit(1).Find(provider).Error; err != nil {
+				return nil, err
+			}
+			if provider.ID == 0 {
+				if !createProviders {
+					return nil, fmt.Errorf("unknown provider %q (use --create-providers to create it)", name)
+				}
+				provider.Name = name
+				if err := tx.Create(provider).Error; err != nil {
+					return nil, err
+				}
+			}
+			providers[name] = provider
+		}
+		tower.Providers = append(tower.Providers, provider)
+	}
+	return tower, nil
+}
+
+// readTowerCSV parses a tower CSV file, matching columns by their header name
+func readTowerCSV(r io.Reader) ([]towerRecord, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"latitude", "longitude"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing required column %q", required)
+		}
+	}
+
+	var records []towerRecord
+	for line, row := range rows[1:] {
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		parseFloat := func(name string) (float64, error) {
+			value := get(name)
+			if value == "" {
+				return 0, nil
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return 0, fmt.Errorf("line %d: invalid %s %q", line+2, name, value)
+			}
+			return parsed, nil
+		}
+
+		record := towerRecord{
+			Kelurahan: get("kelurahan"),
+			Kecamatan: get("kecamatan"),
+			Address:   get("address"),
+			Tipe:      get("tipe"),
+			Status:    get("status"),
+		}
+		if record.Latitude, err = parseFloat("latitude"); err != nil {
+			return nil, err
+		}
+		if record.Longitude, err = parseFloat("longitude"); err != nil {
+			return nil, err
+		}
+		if record.Tinggi, err = parseFloat("tinggi"); err != nil {
+			return nil, err
+		}
+		if providers := get("providers"); providers != "" {
+			record.Providers = strings.Split(providers, ";")
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Export implements the "export" subcommand, which writes towers, providers or blankspot areas as CSV or JSON
+func Export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	resource := flags.String("resource", "towers", "what to export: towers, providers or blankspots")
+	format := flags.String("format", "json", "csv or json")
+	out := flags.String("out", "", "output file (default: standard output)")
+	flags.Parse(args)
+
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unsupported format %q, expected csv or json", *format)
+	}
+	if err := InitDatabase(false); err != nil {
+		return err
+	}
+
+	var header []string
+	var rows [][]string
+	var data interface{}
+
+	switch *resource {
+	case "towers":
+		var towers []models.Tower
+		if err := database.DB.Preload("Providers").Order("id").Find(&towers).Error; err != nil {
+			return err
+		}
+		records := make([]towerRecord, 0, len(towers))
+		for _, tower := range towers {
+			record := towerRecord{
+				ID:        tower.ID,
+				Latitude:  tower.Latitude,
+				Longitude: tower.Longitude,
+				Kelurahan: tower.Kelurahan,
+				Kecamatan: tower.Kecamatan,
+				Address:   tower.Address,
+				Tinggi:    tower.Tinggi,
+				Tipe:      tower.Tipe,
+				Status:    tower.Status,
+				PhotoURL:  tower.PhotoURL,
+				Providers: []string{},
+			}
+			for _, provider := range tower.Providers {
+				record.Providers = append(record.Providers, provider.Name)
+			}
+			records = append(records, record)
+			rows = append(rows, record.csvRow())
+		}
+		header, data = towerCSVHeader, records
+	case "providers":
+		var providers []models.Provider
+		if err := database.DB.Order("id").Find(&providers).Error; err != nil {
+			return err
+		}
+		for _, provider := range providers {
+			rows = append(rows, []string{strconv.FormatUint(uint64(provider.ID), 10), provider.Name, provider.Address})
+		}
+		header, data = []string{"id", "name", "address"}, providers
+	case "blankspots":
+		var areas []models.BlankspotArea
+		if err := database.DB.Order("id").Find(&areas).Error; err != nil {
+			return err
+		}
+		for _, area := range areas {
+			rows = append(rows, []string{strconv.FormatUint(uint64(area.ID), 10), area.Name, area.Kelurahan, area.Type, area.Color, area.Coordinates})
+		}
+		header, data = []string{"id", "name", "kelurahan", "type", "color", "coordinates"}, areas
+	default:
+		return fmt.Errorf("unknown resource %q, expected towers, providers or blankspots", *resource)
+	}
+
+	dst := os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		dst = file
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(dst)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	}
+
+	writer := csv.NewWriter(dst)
+	writer.Write(header)
+	writer.WriteAll(rows)
+	return writer.Error()
+}
@@ -0,0 +1,606 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Supported database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Config holds every setting of the application. It is loaded once at startup by Load.
+type Config struct {
+	Port      int
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Auth      AuthConfig
+	CORS      CORSConfig
+	Upload    UploadConfig
+	Storage   StorageConfig
+	RateLimit RateLimitConfig
+	Reconcile ReconcileConfig
+	Backup    BackupConfig
+	Metrics   MetricsConfig
+	Log       LogConfig
+	Mail      MailConfig
+	Approval  ApprovalConfig
+	OIDC      OIDCConfig
+}
+
+// ServerConfig hardens the HTTP server against slow or oversized requests
+type ServerConfig struct {
+	ReadTimeout       time.Duration // Reading the whole request, including uploads
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration // Writing the response; backup downloads use Backup.DownloadTimeout
+	IdleTimeout       time.Duration // Keep-alive connections between requests
+	MaxHeaderBytes    int
+	MaxBodyBytes      int64         // Defaults to the upload limit plus room for the other form fields
+	ShutdownTimeout   time.Duration // How long in-flight requests may take to finish on shutdown
+}
+
+// DatabaseConfig selects and configures the database
+type DatabaseConfig struct {
+	Driver      string // DriverSQLite or DriverPostgres
+	DSN         string // File path for SQLite, connection URL for PostgreSQL
+	AutoMigrate bool   // Apply pending migrations on startup instead of refusing to start
+}
+
+// AuthConfig configures authentication tokens and password hashing
+type AuthConfig struct {
+	JWTSecret  string
+	TokenTTL   time.Duration
+	BcryptCost int
+
+	JWTSigningKeyFile   string   // PEM private key (RSA or Ed25519) tokens are signed with; HS256 with JWTSecret when empty
+	JWTPreviousKeyFiles []string // PEM keys that signed tokens before, still accepted so rotation logs nobody out
+	JWTPreviousSecrets  []string // Former JWT_SECRET_KEY values, or the current one after switching to a key file
+	JWTIssuer           string
+	JWTAudience         string
+
+	PasswordMinLength     int
+	PasswordClasses       []string // Character classes every password must contain: "lower", "upper", "digit", "symbol" or just "none"
+	PasswordBlocklistFile string   // Extra common or breached passwords, one per line, on top of the built-in list
+
+	LockoutThreshold int           // Consecutive failed logins before an account is locked
+	LockoutBase      time.Duration // First lockout; doubled for every further failure
+	LockoutMax       time.Duration // Upper bound of a lockout
+	AlertDistinctIPs int           // Distinct IPs logging into one account within AlertWindow that raise an alert
+	AlertWindow      time.Duration
+
+	PasswordResetTTL time.Duration // How long a password reset token stays valid
+	PasswordResetURL string        // Page of the frontend reset links point to; the token is appended as ?token=
+}
+
+// CORSConfig lists the origins allowed to call the API from a browser
+type CORSConfig struct {
+	AllowOrigins []string
+}
+
+// UploadConfig bounds image uploads
+type UploadConfig struct {
+	MaxBytes               int64   // Maximum size of an uploaded file
+	MaxPixels              int     // Maximum width * height, checked before the image is decoded
+	MaxEdge                int     // Longest edge of the stored original; larger images are scaled down
+	PhotoMaxDistanceMeters float64 // Photos taken further from their tower are flagged
+}
+
+// StorageConfig selects and configures the upload storage backend
+type StorageConfig struct {
+	Driver       string // "local", "s3" or "memory"
+	UploadDir    string
+	URLPrefix    string // Base URL of uploads, a path or a full URL when a proxy or CDN serves them
+	SignedURLs   bool
+	SignedURLTTL time.Duration
+	SigningKey   string // Defaults to the JWT secret
+	S3           S3Config
+}
+
+// URLPath returns the path the server serves uploads under: the path of URLPrefix without
+// trailing slash, or "" if it has none
+func (c StorageConfig) URLPath() string {
+	parsed, err := url.Parse(c.URLPrefix)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(parsed.Path, "/")
+}
+
+// S3Config configures the S3 storage backend
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	PublicURL string
+}
+
+// RateLimitConfig selects the rate limiter backend and the limits of rate limited endpoints
+type RateLimitConfig struct {
+	Backend               string // "memory" or "redis"
+	RedisURL              string
+	MaxKeys               int // Keys tracked by the memory backend before the least recently used are evicted
+	LoginPerIP            RateLimit
+	LoginPerEmail         RateLimit // Limits brute force against one account from many addresses
+	ChangePasswordPerUser RateLimit
+	PasswordReset         RateLimit // Applied per IP and per email to forgot-password, per IP to reset-password
+}
+
+// RateLimit allows Count requests per Period, written as "<count>/<period>" (e.g. "5/15m")
+type RateLimit struct {
+	Count  int
+	Period time.Duration
+}
+
+// ReconcileConfig schedules the upload reconciler. It is disabled when Interval is zero.
+type ReconcileConfig struct {
+	Interval    time.Duration
+	Action      string
+	GracePeriod time.Duration
+
+	QuarantineRetention time.Duration // Quarantined files older than this are purged; zero keeps them
+}
+
+// BackupConfig schedules backups. They are disabled when Interval is zero.
+type BackupConfig struct {
+	Interval        time.Duration
+	Dir             string
+	Retention       int
+	DownloadTimeout time.Duration // Building and sending a downloaded archive, replacing HTTP_WRITE_TIMEOUT
+}
+
+// MetricsConfig protects the Prometheus endpoint. It is public when Token is empty.
+type MetricsConfig struct {
+	Token string
+}
+
+// LogConfig controls structured logging
+type LogConfig struct {
+	Level  string // "debug", "info", "warn" or "error"
+	Format string // "json" or "text"
+}
+
+// MailConfig selects how outgoing emails are delivered
+type MailConfig struct {
+	Driver string // "smtp", "file", "log" or "none", which drops emails
+	From   string
+	Dir    string // Directory the file driver writes messages to
+	SMTP   SMTPConfig
+}
+
+// SMTPConfig configures the smtp mail driver. Authentication is skipped when Username is empty.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// ApprovalConfig controls which tower edits need a second user's approval. Edits by operator
+// accounts always do.
+type ApprovalConfig struct {
+	FourEyes bool // Relocations and dismantlements by staff need approval by a different approver
+}
+
+// OIDCConfig configures single sign-on through an OpenID Connect identity provider. It is disabled
+// when IssuerURL is empty. Accounts are created on first sign-in, and their role is taken from the
+// groups claim on every sign-in.
+type OIDCConfig struct {
+	IssuerURL        string
+	ClientID         string
+	ClientSecret     string
+	RedirectURL      string // Callback URL registered with the identity provider
+	Scopes           []string
+	LoginRedirectURL string            // Frontend page the callback redirects to, with #token= or #error= appended
+	GroupsClaim      string            // ID token claim listing the groups of the user
+	StaffGroups      []string          // Members get staff accounts
+	ApproverGroups   []string          // Members may review change requests in four-eyes mode
+	ProviderGroups   map[string]string // Group to provider name; members get operator accounts of that provider
+}
+
+// Enabled reports whether single sign-on is configured
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != ""
+}
+
+// Current is the configuration loaded by Load
+var Current *Config
+
+// Load reads the configuration from the environment, after loading the file named by CONFIG_FILE
+// (default ".env") if it exists. Variables already set in the environment take precedence over the
+// file. The configuration is validated and stored in Current.
+func Load() (*Config, error) {
+	file := os.Getenv("CONFIG_FILE")
+	if file == "" {
+		file = ".env"
+	}
+	if err := godotenv.Load(file); err != nil {
+		if os.Getenv("CONFIG_FILE") != "" {
+			return nil, fmt.Errorf("failed to load %s: %w", file, err)
+		}
+		log.Println("No .env file found, using environment variables directly")
+	}
+
+	env := &envReader{}
+	cfg := &Config{
+		Port: env.int("PORT", 8080),
+		Server: ServerConfig{
+			ReadTimeout:       env.duration("HTTP_READ_TIMEOUT", time.Minute),
+			ReadHeaderTimeout: env.duration("HTTP_READ_HEADER_TIMEOUT", 10*time.Second),
+			WriteTimeout:      env.duration("HTTP_WRITE_TIMEOUT", 2*time.Minute),
+			IdleTimeout:       env.duration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+			MaxHeaderBytes:    env.int("HTTP_MAX_HEADER_BYTES", 1<<20), // 1 MB
+			MaxBodyBytes:      int64(env.int("HTTP_MAX_BODY_BYTES", 0)),
+			ShutdownTimeout:   env.duration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		},
+		Database: DatabaseConfig{
+			Driver:      env.string("DB_DRIVER", ""),
+			DSN:         env.string("DATABASE_URL", ""),
+			AutoMigrate: env.bool("AUTO_MIGRATE", false),
+		},
+		Auth: AuthConfig{
+			JWTSecret:  env.string("JWT_SECRET_KEY", ""),
+			TokenTTL:   env.duration("JWT_TTL", 24*time.Hour),
+			BcryptCost: env.int("BCRYPT_COST", 14),
+
+			JWTSigningKeyFile:   env.string("JWT_SIGNING_KEY_FILE", ""),
+			JWTPreviousKeyFiles: env.list("JWT_PREVIOUS_KEY_FILES", nil),
+			JWTPreviousSecrets:  env.list("JWT_PREVIOUS_SECRETS", nil),
+			JWTIssuer:           env.string("JWT_ISSUER", "tower-tracker"),
+			JWTAudience:         env.string("JWT_AUDIENCE", "tower-tracker-api"),
+
+			PasswordMinLength:     env.int("PASSWORD_MIN_LENGTH", 8),
+			PasswordClasses:       env.list("PASSWORD_CLASSES", []string{"lower", "upper", "digit"}),
+			PasswordBlocklistFile: env.string("PASSWORD_BLOCKLIST_FILE", ""),
+
+			LockoutThreshold: env.int("LOGIN_LOCKOUT_THRESHOLD", 5),
+			LockoutBase:      env.duration("LOGIN_LOCKOUT_BASE", time.Minute),
+			LockoutMax:       env.duration("LOGIN_LOCKOUT_MAX", time.Hour),
+			AlertDistinctIPs: env.int("LOGIN_ALERT_DISTINCT_IPS", 5),
+			AlertWindow:      env.duration("LOGIN_ALERT_WINDOW", 24*time.Hour),
+
+			PasswordResetTTL: env.duration("PASSWORD_RESET_TTL", time.Hour),
+			PasswordResetURL: env.string("PASSWORD_RESET_URL", "http://localhost:5173/reset-password"),
+		},
+		CORS: CORSConfig{
+			AllowOrigins: env.list("CORS_ALLOWED_ORIGINS", []string{"http://localhost:5173", "http://localhost:8080"}),
+		},
+		Upload: UploadConfig{
+			MaxBytes:               int64(env.int("UPLOAD_MAX_BYTES", 10<<20)), // 10 MB
+			MaxPixels:              env.int("UPLOAD_MAX_PIXELS", 50_000_000),   // 50 megapixels
+			MaxEdge:                env.int("UPLOAD_MAX_EDGE", 2560),
+			PhotoMaxDistanceMeters: env.float("PHOTO_MAX_DISTANCE_METERS", 200),
+		},
+		Storage: StorageConfig{
+			Driver:       env.string("STORAGE_DRIVER", "local"),
+			UploadDir:    env.string("UPLOAD_DIR", "uploads"),
+			URLPrefix:    env.string("UPLOAD_URL_PREFIX", "/uploads"),
+			SignedURLs:   env.bool("STORAGE_SIGNED_URLS", false),
+			SignedURLTTL: env.duration("STORAGE_SIGNED_URL_TTL", 15*time.Minute),
+			SigningKey:   env.string("STORAGE_SIGNING_KEY", ""),
+			S3: S3Config{
+				Endpoint:  env.string("S3_ENDPOINT", ""),
+				Region:    env.string("S3_REGION", ""),
+				Bucket:    env.string("S3_BUCKET", ""),
+				AccessKey: env.string("S3_ACCESS_KEY", ""),
+				SecretKey: env.string("S3_SECRET_KEY", ""),
+				UseSSL:    env.bool("S3_USE_SSL", true),
+				PublicURL: env.string("S3_PUBLIC_URL", ""),
+			},
+		},
+		RateLimit: RateLimitConfig{
+			Backend:               env.string("RATE_LIMIT_BACKEND", "memory"),
+			RedisURL:              env.string("REDIS_URL", ""),
+			MaxKeys:               env.int("RATE_LIMIT_MAX_KEYS", 100_000),
+			LoginPerIP:            env.rateLimit("RATE_LIMIT_LOGIN_IP", RateLimit{20, time.Minute}),
+			LoginPerEmail:         env.rateLimit("RATE_LIMIT_LOGIN_EMAIL", RateLimit{5, 15 * time.Minute}),
+			ChangePasswordPerUser: env.rateLimit("RATE_LIMIT_CHANGE_PASSWORD", RateLimit{5, 15 * time.Minute}),
+			PasswordReset:         env.rateLimit("RATE_LIMIT_PASSWORD_RESET", RateLimit{5, time.Hour}),
+		},
+		Reconcile: ReconcileConfig{
+			Interval:    env.duration("RECONCILE_INTERVAL", 0),
+			Action:      env.string("RECONCILE_ACTION", "report"),
+			GracePeriod: env.duration("RECONCILE_GRACE_PERIOD", 24*time.Hour),
+
+			QuarantineRetention: env.duration("RECONCILE_QUARANTINE_RETENTION", 30*24*time.Hour),
+		},
+		Backup: BackupConfig{
+			Interval:        env.duration("BACKUP_INTERVAL", 0),
+			Dir:             env.string("BACKUP_DIR", "backups"),
+			Retention:       env.int("BACKUP_RETENTION", 7),
+			DownloadTimeout: env.duration("BACKUP_DOWNLOAD_TIMEOUT", time.Hour),
+		},
+		Metrics: MetricsConfig{
+			Token: env.string("METRICS_TOKEN", ""),
+		},
+		Log: LogConfig{
+			Level:  strings.ToLower(env.string("LOG_LEVEL", "info")),
+			Format: strings.ToLower(env.string("LOG_FORMAT", "json")),
+		},
+		Mail: MailConfig{
+			Driver: strings.ToLower(env.string("MAIL_DRIVER", "none")),
+			From:   env.string("MAIL_FROM", "Tower Tracker <no-reply@localhost>"),
+			Dir:    env.string("MAIL_DIR", "mail"),
+			SMTP: SMTPConfig{
+				Host:     env.string("SMTP_HOST", "localhost"),
+				Port:     env.int("SMTP_PORT", 25),
+				Username: env.string("SMTP_USERNAME", ""),
+				Password: env.string("SMTP_PASSWORD", ""),
+			},
+		},
+		Approval: ApprovalConfig{
+			FourEyes: env.bool("FOUR_EYES_APPROVAL", false),
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:        strings.TrimSuffix(env.string("OIDC_ISSUER_URL", ""), "/"),
+			ClientID:         env.string("OIDC_CLIENT_ID", ""),
+			ClientSecret:     env.string("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:      env.string("OIDC_REDIRECT_URL", "http://localhost:8080/api/auth/oidc/callback"),
+			Scopes:           env.list("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+			LoginRedirectURL: env.string("OIDC_LOGIN_REDIRECT_URL", "http://localhost:5173/login/callback"),
+			GroupsClaim:      env.string("OIDC_GROUPS_CLAIM", "groups"),
+			StaffGroups:      env.list("OIDC_STAFF_GROUPS", nil),
+			ApproverGroups:   env.list("OIDC_APPROVER_GROUPS", nil),
+			ProviderGroups:   env.pairs("OIDC_PROVIDER_GROUPS"),
+		},
+	}
+	cfg.Database.resolve(env.string("DB_PATH", ""))
+	if cfg.Server.MaxBodyBytes == 0 {
+		cfg.Server.MaxBodyBytes = cfg.Upload.MaxBytes + 1<<20
+	}
+	if cfg.Storage.SigningKey == "" {
+		cfg.Storage.SigningKey = cfg.Auth.JWTSecret
+	}
+
+	if err := errors.Join(append(env.errs, cfg.Validate()...)...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	Current = cfg
+	return cfg, nil
+}
+
+// resolve fills in the driver and DSN. Without DB_DRIVER, a postgres:// URL selects PostgreSQL
+// and anything else SQLite. For SQLite, DATABASE_URL is the file path, with DB_PATH still
+// honoured for existing deployments.
+func (c *DatabaseConfig) resolve(path string) {
+	if c.Driver == "" {
+		c.Driver = DriverSQLite
+		if strings.HasPrefix(c.DSN, "postgres://") || strings.HasPrefix(c.DSN, "postgresql://") {
+			c.Driver = DriverPostgres
+		}
+	}
+	if c.Driver == DriverSQLite && c.DSN == "" {
+		c.DSN = path
+		if c.DSN == "" {
+			c.DSN = "backend/tower_tracker.db" // Default for local development
+		}
+	}
+}
+
+// Validate checks the configuration for values the application cannot start with
+func (c *Config) Validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Auth.JWTSecret != "", "JWT_SECRET_KEY must be set")
+	check(c.Port > 0 && c.Port < 65536, "PORT must be between 1 and 65535")
+	check(c.Server.ReadTimeout > 0 && c.Server.ReadHeaderTimeout > 0 && c.Server.WriteTimeout > 0 && c.Server.IdleTimeout > 0,
+		"HTTP_READ_TIMEOUT, HTTP_READ_HEADER_TIMEOUT, HTTP_WRITE_TIMEOUT and HTTP_IDLE_TIMEOUT must be positive")
+	check(c.Server.MaxHeaderBytes > 0, "HTTP_MAX_HEADER_BYTES must be positive")
+	check(c.Server.MaxBodyBytes > c.Upload.MaxBytes, "HTTP_MAX_BODY_BYTES must be larger than UPLOAD_MAX_BYTES")
+	check(c.Server.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	check(c.Auth.TokenTTL > 0, "JWT_TTL must be positive")
+	check(c.Auth.JWTIssuer != "" && c.Auth.JWTAudience != "", "JWT_ISSUER and JWT_AUDIENCE must not be empty")
+	check(c.Auth.BcryptCost >= 10 && c.Auth.BcryptCost <= 31, "BCRYPT_COST must be between 10 and 31")
+	// bcrypt only looks at the first 72 bytes of a password
+	check(c.Auth.PasswordMinLength >= 6 && c.Auth.PasswordMinLength <= 72, "PASSWORD_MIN_LENGTH must be between 6 and 72")
+	for _, class := range c.Auth.PasswordClasses {
+		check(class == "lower" || class == "upper" || class == "digit" || class == "symbol" || class == "none",
+			`PASSWORD_CLASSES entry %q must be "lower", "upper", "digit", "symbol" or "none"`, class)
+	}
+	check(c.Auth.LockoutThreshold > 0, "LOGIN_LOCKOUT_THRESHOLD must be positive")
+	check(c.Auth.LockoutBase > 0 && c.Auth.LockoutMax >= c.Auth.LockoutBase, "LOGIN_LOCKOUT_BASE must be positive and not exceed LOGIN_LOCKOUT_MAX")
+	check(c.Auth.AlertDistinctIPs > 1, "LOGIN_ALERT_DISTINCT_IPS must be at least 2")
+	check(c.Auth.AlertWindow > 0, "LOGIN_ALERT_WINDOW must be positive")
+	check(c.Auth.PasswordResetTTL > 0, "PASSWORD_RESET_TTL must be positive")
+	check(strings.HasPrefix(c.Auth.PasswordResetURL, "http://") || strings.HasPrefix(c.Auth.PasswordResetURL, "https://"), "PASSWORD_RESET_URL must start with http:// or https://")
+	for _, origin := range c.CORS.AllowOrigins {
+		check(strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://"), "CORS_ALLOWED_ORIGINS entry %q must start with http:// or https://", origin)
+	}
+
+	switch c.Database.Driver {
+	case DriverSQLite:
+	case DriverPostgres:
+		check(c.Database.DSN != "", "DATABASE_URL is required when DB_DRIVER is %s", DriverPostgres)
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER must be %q or %q", DriverSQLite, DriverPostgres))
+	}
+
+	check(c.Upload.MaxBytes > 0, "UPLOAD_MAX_BYTES must be positive")
+	check(c.Upload.MaxPixels > 0, "UPLOAD_MAX_PIXELS must be positive")
+	check(c.Upload.MaxEdge > 0, "UPLOAD_MAX_EDGE must be positive")
+	check(c.Upload.PhotoMaxDistanceMeters > 0, "PHOTO_MAX_DISTANCE_METERS must be positive")
+
+	switch c.Storage.Driver {
+	case "local", "memory":
+	case "s3":
+		check(c.Storage.S3.Endpoint != "" && c.Storage.S3.Bucket != "", "S3_ENDPOINT and S3_BUCKET are required when STORAGE_DRIVER is s3")
+	default:
+		errs = append(errs, errors.New(`STORAGE_DRIVER must be "local", "s3" or "memory"`))
+	}
+	check(c.Storage.SignedURLTTL > 0, "STORAGE_SIGNED_URL_TTL must be positive")
+	uploadPath := c.Storage.URLPath()
+	check(strings.HasPrefix(uploadPath, "/") && uploadPath != "/api" && !strings.HasPrefix(uploadPath, "/api/"),
+		"UPLOAD_URL_PREFIX must have a path other than / and /api, e.g. /uploads")
+
+	switch c.RateLimit.Backend {
+	case "memory":
+		check(c.RateLimit.MaxKeys > 0, "RATE_LIMIT_MAX_KEYS must be positive")
+	case "redis":
+		check(c.RateLimit.RedisURL != "", "REDIS_URL is required when RATE_LIMIT_BACKEND is redis")
+	default:
+		errs = append(errs, errors.New(`RATE_LIMIT_BACKEND must be "memory" or "redis"`))
+	}
+
+	check(c.Reconcile.Interval >= 0, "RECONCILE_INTERVAL must not be negative")
+	check(c.Reconcile.Action == "report" || c.Reconcile.Action == "quarantine" || c.Reconcile.Action == "delete", `RECONCILE_ACTION must be "report", "quarantine" or "delete"`)
+	check(c.Reconcile.QuarantineRetention >= 0, "RECONCILE_QUARANTINE_RETENTION must not be negative")
+	check(c.Backup.Interval >= 0, "BACKUP_INTERVAL must not be negative")
+	check(c.Backup.Retention > 0, "BACKUP_RETENTION must be positive")
+	check(c.Backup.DownloadTimeout > 0, "BACKUP_DOWNLOAD_TIMEOUT must be positive")
+	check(c.Log.Level == "debug" || c.Log.Level == "info" || c.Log.Level == "warn" || c.Log.Level == "error", `LOG_LEVEL must be "debug", "info", "warn" or "error"`)
+	check(c.Log.Format == "json" || c.Log.Format == "text", `LOG_FORMAT must be "json" or "text"`)
+
+	switch c.Mail.Driver {
+	case "none", "log":
+	case "file":
+		check(c.Mail.Dir != "", "MAIL_DIR is required when MAIL_DRIVER is file")
+	case "smtp":
+		check(c.Mail.SMTP.Host != "", "SMTP_HOST is required when MAIL_DRIVER is smtp")
+		check(c.Mail.SMTP.Port > 0 && c.Mail.SMTP.Port < 65536, "SMTP_PORT must be between 1 and 65535")
+	default:
+		errs = append(errs, errors.New(`MAIL_DRIVER must be "smtp", "file", "log" or "none"`))
+	}
+	_, err := mail.ParseAddress(c.Mail.From)
+	check(err == nil, "MAIL_FROM must be an email address: %v", err)
+
+	if c.OIDC.Enabled() {
+		check(strings.HasPrefix(c.OIDC.IssuerURL, "http://") || strings.HasPrefix(c.OIDC.IssuerURL, "https://"), "OIDC_ISSUER_URL must start with http:// or https://")
+		check(c.OIDC.ClientID != "", "OIDC_CLIENT_ID is required when OIDC_ISSUER_URL is set")
+		check(strings.HasPrefix(c.OIDC.RedirectURL, "http://") || strings.HasPrefix(c.OIDC.RedirectURL, "https://"), "OIDC_REDIRECT_URL must start with http:// or https://")
+		check(strings.HasPrefix(c.OIDC.LoginRedirectURL, "http://") || strings.HasPrefix(c.OIDC.LoginRedirectURL, "https://"), "OIDC_LOGIN_REDIRECT_URL must start with http:// or https://")
+		check(slices.Contains(c.OIDC.Scopes, "openid"), `OIDC_SCOPES must include "openid"`)
+		check(len(c.OIDC.StaffGroups) > 0 || len(c.OIDC.ProviderGroups) > 0, "OIDC_STAFF_GROUPS or OIDC_PROVIDER_GROUPS must be set, otherwise nobody can sign in")
+	}
+	return errs
+}
+
+// envReader reads typed environment variables, collecting parse errors instead of failing on the first
+type envReader struct {
+	errs []error
+}
+
+func (r *envReader) string(name, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func (r *envReader) int(name string, fallback int) int {
+	value := r.string(name, "")
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: %q is not an integer", name, value))
+	}
+	return parsed
+}
+
+func (r *envReader) float(name string, fallback float64) float64 {
+	value := r.string(name, "")
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: %q is not a number", name, value))
+	}
+	return parsed
+}
+
+func (r *envReader) bool(name string, fallback bool) bool {
+	value := r.string(name, "")
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: %q is not a boolean", name, value))
+	}
+	return parsed
+}
+
+func (r *envReader) duration(name string, fallback time.Duration) time.Duration {
+	value := r.string(name, "")
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: %q is not a duration (e.g. \"30s\", \"24h\")", name, value))
+	}
+	return parsed
+}
+
+// rateLimit reads a limit written as "<count>/<period>", where the period is a duration or a bare
+// unit ("10/1m", "5/15m", "100/h")
+func (r *envReader) rateLimit(name string, fallback RateLimit) RateLimit {
+	value := r.string(name, "")
+	if value == "" {
+		return fallback
+	}
+
+	count, period, found := strings.Cut(value, "/")
+	if found && period != "" && !strings.ContainsAny(period[:1], "0123456789") {
+		period = "1" + period
+	}
+	parsedCount, countErr := strconv.Atoi(count)
+	parsedPeriod, periodErr := time.ParseDuration(period)
+	if !found || countErr != nil || periodErr != nil || parsedCount <= 0 || parsedPeriod <= 0 {
+		r.errs = append(r.errs, fmt.Errorf("%s: %q is not a rate limit (e.g. \"5/15m\")", name, value))
+		return fallback
+	}
+	return RateLimit{Count: parsedCount, Period: parsedPeriod}
+}
+
+// pairs reads a comma separated list of "<key>=<value>" pairs
+func (r *envReader) pairs(name string) map[string]string {
+	pairs := map[string]string{}
+	for _, item := range r.list(name, nil) {
+		key, value, found := strings.Cut(item, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !found || key == "" || value == "" {
+			r.errs = append(r.errs, fmt.Errorf("%s: %q is not a <key>=<value> pair", name, item))
+			continue
+		}
+		pairs[key] = value
+	}
+	return pairs
+}
+
+// list reads a comma separated list
+func (r *envReader) list(name string, fallback []string) []string {
+	value := r.string(name, "")
+	if value == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+)
+
+type APIKeyInput struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"` // Optional, RFC 3339
+}
+
+// GetAPIKeys lists the API keys of the current user
+func GetAPIKeys(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	var apiKeys []models.APIKey
+	if err := database.DB.Where("user_id = ?", userID).Order("id").Find(&apiKeys).Error; err != nil {
+		helper.Logger(c).Error("Failed to fetch API keys", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch API keys")
+		return
+	}
+	helper.SendSuccessResponse(c, http.StatusOK, "API keys fetched successfully", apiKeys)
+}
+
+// CreateAPIKey creates an API key for the current user. The key is only returned by this call.
+func CreateAPIKey(c *gin.Context) {
+	var input APIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, scope := range input.Scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			helper.SendErrorResponse(c, http.StatusBadRequest, "Unknown scope "+scope+", valid scopes are "+strings.Join(models.APIKeyScopes, ", "))
+			return
+		}
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+
+	key, prefix, hash, err := helper.GenerateAPIKey()
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to generate API key")
+		return
+	}
+
+	scopes := slices.Clone(input.Scopes)
+	slices.Sort(scopes)
+	apiKey := models.APIKey{
+		UserID:    c.MustGet("user_id").(uint),
+		Name:      input.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    strings.Join(slices.Compact(scopes), ","),
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := database.DB.Create(&apiKey).Error; err != nil {
+		helper.Logger(c).Error("Failed to create API key", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+	helper.Logger(c).Info("API key created", "api_key_prefix", apiKey.Prefix, "scopes", apiKey.Scopes)
+
+	helper.SendSuccessResponse(c, http.StatusCreated, "API key created, store the key now as it cannot be shown again",
+		gin.H{"key": key, "api_key": apiKey})
+}
+
+// DeleteAPIKey revokes an API key of the current user
+func DeleteAPIKey(c *gin.Context) {
+	var apiKey models.APIKey
+	if err := database.DB.Where("user_id = ?", c.MustGet("user_id").(uint)).First(&apiKey, c.Param("id")).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	if err := database.DB.Delete(&apiKey).Error; err != nil {
+		helper.Logger(c).Error("Failed to revoke API key", "api_key_prefix", apiKey.Prefix, "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+	helper.Logger(c).Info("API key revoked", "api_key_prefix", apiKey.Prefix)
+
+	helper.SendSuccessResponse(c, http.StatusOK, "API key revoked", nil)
+}
@@ -1,9 +1,8 @@
 package controllers
 
 import (
-	"log"
 	"net/http"
-	"regexp"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/user/tower-tracker-bima/backend/database"
@@ -14,15 +13,21 @@ import (
 type RegisterInput struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 }
 
+// Register creates a staff account on behalf of a signed-in staff user. Operator accounts are made
+// from it by linking them to a provider.
 func Register(c *gin.Context) {
 	var input RegisterInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := helper.ValidatePassword(input.Password, input.Email); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	hashedPassword, err := helper.HashPassword(input.Password)
 	if err != nil {
@@ -37,6 +42,7 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	helper.Logger(c).Info("User registered", "target_user_id", user.ID)
 	helper.SendSuccessResponse(c, http.StatusOK, "Registration successful", nil)
 }
 
@@ -48,22 +54,41 @@ type LoginInput struct {
 func Login(c *gin.Context) {
 	var input LoginInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		log.Printf("Login Request Binding Error: %v", err)
+		helper.Logger(c).Warn("Invalid login request", "error", err)
 		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	var user models.User
 	if err := database.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+		helper.DummyPasswordCheck(input.Password)
+		recordLoginEvent(c, nil, input.Email, false, models.LoginFailureUnknownEmail)
+		helper.SendErrorResponse(c, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+
+	// Locked accounts are refused whatever the password, so guessing cannot continue. The response,
+	// and its timing, are the same as for a wrong password, so that it does not reveal the account exists.
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		helper.DummyPasswordCheck(input.Password)
+		recordLoginEvent(c, &user.ID, input.Email, false, models.LoginFailureLocked)
+		helper.Logger(c).Info("Login refused for locked account", "target_user_id", user.ID, "locked_until", *user.LockedUntil)
 		helper.SendErrorResponse(c, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
 
 	if !helper.CheckPasswordHash(input.Password, user.Password) {
+		recordLoginEvent(c, &user.ID, input.Email, false, models.LoginFailureWrongPassword)
+		registerFailedLogin(c, &user)
 		helper.SendErrorResponse(c, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
 
+	resetFailedLogins(c, &user)
+	rehashPassword(c, &user, input.Password)
+	recordLoginEvent(c, &user.ID, input.Email, true, "")
+	checkLoginAnomalies(c, &user)
+
 	token, err := helper.GenerateJWT(user.ID)
 	if err != nil {
 		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to generate token")
@@ -73,6 +98,23 @@ func Login(c *gin.Context) {
 	helper.SendSuccessResponse(c, http.StatusOK, "Login successful", gin.H{"token": token})
 }
 
+// rehashPassword replaces the password hash of user when BCRYPT_COST has changed since it was created.
+// It runs after a successful login, the only time the plain password is known.
+func rehashPassword(c *gin.Context, user *models.User, password string) {
+	if !helper.NeedsRehash(user.Password) {
+		return
+	}
+	hashedPassword, err := helper.HashPassword(password)
+	if err == nil {
+		err = database.DB.Model(user).UpdateColumn("password", hashedPassword).Error
+	}
+	if err != nil {
+		helper.Logger(c).Error("Failed to rehash password", "target_user_id", user.ID, "error", err)
+		return
+	}
+	helper.Logger(c).Info("Password rehashed with the configured bcrypt cost", "target_user_id", user.ID)
+}
+
 type ChangePasswordInput struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
 	NewPassword     string `json:"new_password" binding:"required"`
@@ -85,24 +127,6 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
-	// Custom validation for new password
-	if len(input.NewPassword) < 6 {
-		helper.SendErrorResponse(c, http.StatusBadRequest, "New password must be at least 6 characters.")
-		return
-	}
-	if ok, _ := regexp.MatchString(`[a-z]`, input.NewPassword); !ok {
-		helper.SendErrorResponse(c, http.StatusBadRequest, "Must have a lowercase letter.")
-		return
-	}
-	if ok, _ := regexp.MatchString(`[A-Z]`, input.NewPassword); !ok {
-		helper.SendErrorResponse(c, http.StatusBadRequest, "Must have an uppercase letter.")
-		return
-	}
-	if ok, _ := regexp.MatchString(`\d`, input.NewPassword); !ok {
-		helper.SendErrorResponse(c, http.StatusBadRequest, "Must have a number.")
-		return
-	}
-
 	userID := c.MustGet("user_id").(uint) // Get user_id from JWT claims
 	var user models.User
 	if err := database.DB.First(&user, userID).Error; err != nil {
@@ -116,6 +140,11 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
+	if err := helper.ValidatePassword(input.NewPassword, user.Email); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Hash new password
 	hashedPassword, err := helper.HashPassword(input.NewPassword)
 	if err != nil {
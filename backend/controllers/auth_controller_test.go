@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+func TestLoginDoesNotRevealLockedAccounts(t *testing.T) {
+	testutil.Setup(t, map[string]string{"LOGIN_LOCKOUT_THRESHOLD": "2"})
+	hash, err := helper.HashPassword("Secret-Password42")
+	require.NoError(t, err)
+	require.NoError(t, database.DB.Create(&models.User{Name: "Jane", Email: "jane@example.com", Password: hash}).Error)
+
+	router := gin.New()
+	router.POST("/api/auth/login", Login)
+	login := func(email, password string) (int, string, string) {
+		w := postJSON(router, "/api/auth/login", `{"email": "`+email+`", "password": "`+password+`"}`)
+		return w.Code, w.Body.String(), w.Header().Get("Retry-After")
+	}
+
+	unknownCode, unknownBody, _ := login("nobody@example.com", "Wrong-Password42")
+	assert.Equal(t, http.StatusUnauthorized, unknownCode)
+	for range 2 {
+		code, body, _ := login("jane@example.com", "Wrong-Password42")
+		assert.Equal(t, unknownCode, code)
+		assert.Equal(t, unknownBody, body)
+	}
+
+	// Even the right password is refused while locked, exactly like an unknown email
+	code, body, retryAfter := login("jane@example.com", "Secret-Password42")
+	assert.Equal(t, unknownCode, code)
+	assert.Equal(t, unknownBody, body)
+	assert.Empty(t, retryAfter)
+
+	var event models.LoginEvent
+	require.NoError(t, database.DB.Order("id desc").First(&event).Error)
+	assert.Equal(t, models.LoginFailureLocked, event.FailureReason)
+}
+
+func TestLoginTimingDoesNotRevealAccounts(t *testing.T) {
+	testutil.Setup(t, map[string]string{"LOGIN_LOCKOUT_THRESHOLD": "1"})
+	hash, err := helper.HashPassword("Secret-Password42")
+	require.NoError(t, err)
+	require.NoError(t, database.DB.Create(&models.User{Name: "Jane", Email: "jane@example.com", Password: hash}).Error)
+
+	router := gin.New()
+	router.POST("/api/auth/login", Login)
+	login := func(email string) time.Duration {
+		start := time.Now()
+		w := postJSON(router, "/api/auth/login", `{"email": "`+email+`", "password": "Wrong-Password42"}`)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		return time.Since(start)
+	}
+
+	start := time.Now()
+	helper.CheckPasswordHash("Wrong-Password42", hash)
+	bcryptTime := time.Since(start)
+
+	login("jane@example.com") // Locks the account
+	assert.Greater(t, login("jane@example.com"), bcryptTime/2, "locked account")
+	assert.Greater(t, login("nobody@example.com"), bcryptTime/2, "unknown email")
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/backup"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/storage"
+)
+
+// DownloadBackup responds with a backup archive of the database and the upload files. The archive
+// is written to a temporary file first, so that a failure can still be reported as an error. Since
+// building it can take longer than HTTP_WRITE_TIMEOUT, the request gets BACKUP_DOWNLOAD_TIMEOUT.
+// The archive holds credential hashes, so the route is limited to approvers and every download is logged.
+func DownloadBackup(c *gin.Context) {
+	deadline := time.Now().Add(config.Current.Backup.DownloadTimeout)
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(deadline); err != nil {
+		helper.Logger(c).Warn("Failed to extend the write deadline of the backup download", "error", err)
+	}
+
+	tmp, err := os.CreateTemp("", "tower-backup-*"+backup.ArchiveExtension)
+	if err != nil {
+		helper.Logger(c).Error("Failed to create backup file", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to create backup")
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	manifest, err := backup.Create(c.Request.Context(), database.DB, storage.Default, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		helper.Logger(c).Error("Failed to create backup", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to create backup")
+		return
+	}
+
+	helper.Logger(c).Info("Backup downloaded", "files", len(manifest.Files), "client_ip", c.ClientIP())
+
+	name := "tower_tracker-" + manifest.CreatedAt.Local().Format("20060102-150405") + backup.ArchiveExtension
+	c.Header("Cache-Control", "no-store")
+	c.Header("Last-Modified", manifest.CreatedAt.Format(http.TimeFormat))
+	c.FileAttachment(tmp.Name(), name)
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/backup"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+func TestDownloadBackupOutlastsWriteTimeout(t *testing.T) {
+	testutil.Setup(t, map[string]string{"BACKUP_DOWNLOAD_TIMEOUT": "1m"})
+	useMemoryStorage(t)
+
+	// Building the archive takes longer than the write timeout of the server
+	router := gin.New()
+	router.GET("/api/admin/backup", func(c *gin.Context) { time.Sleep(100 * time.Millisecond) }, DownloadBackup)
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/admin/backup")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), backup.ArchiveExtension)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.NotEmpty(t, data)
+}
+
+func TestDownloadBackupHidesErrors(t *testing.T) {
+	testutil.Setup(t, nil)
+	useMemoryStorage(t)
+	previous := database.Driver
+	database.Driver = database.DriverPostgres // Backups refuse anything but SQLite
+	t.Cleanup(func() { database.Driver = previous })
+
+	router := gin.New()
+	router.GET("/api/admin/backup", DownloadBackup)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/backup", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to create backup")
+	assert.NotContains(t, w.Body.String(), "SQLite", "the cause is only logged")
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -56,16 +57,20 @@ func GetBlankspotArea(c *gin.Context) {
 		helper.SendErrorResponse(c, http.StatusNotFound, "Blankspot area not found")
 		return
 	}
+	helper.SetETag(c, blankspotArea.Version)
 	helper.SendSuccessResponse(c, http.StatusOK, "Blankspot area fetched successfully", blankspotArea)
 }
 
-// UpdateBlankspotArea handles updating an existing blankspot area
+// UpdateBlankspotArea handles updating an existing blankspot area. If-Match must carry the area's ETag.
 func UpdateBlankspotArea(c *gin.Context) {
 	var blankspotArea models.BlankspotArea
 	if err := database.DB.First(&blankspotArea, c.Param("id")).Error; err != nil {
 		helper.SendErrorResponse(c, http.StatusNotFound, "Blankspot area not found")
 		return
 	}
+	if !checkIfMatch(c, blankspotArea.Version, func() { sendVersionConflict(c, blankspotArea.Version, blankspotArea) }) {
+		return
+	}
 
 	var input BlankspotAreaInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -80,10 +85,59 @@ func UpdateBlankspotArea(c *gin.Context) {
 		"Type":        input.Type,
 		"Color":       input.Color,
 	}
-	database.DB.Model(&blankspotArea).Updates(updateData)
+	if !applyBlankspotAreaUpdates(c, &blankspotArea, updateData) {
+		return
+	}
+	helper.SetETag(c, blankspotArea.Version)
 	helper.SendSuccessResponse(c, http.StatusOK, "Blankspot area updated successfully", blankspotArea)
 }
 
+// PatchBlankspotArea changes the fields of a blankspot area named in a JSON Merge Patch (RFC 7396),
+// leaving the others as they are. If-Match must carry the area's ETag.
+func PatchBlankspotArea(c *gin.Context) {
+	var blankspotArea models.BlankspotArea
+	if err := database.DB.First(&blankspotArea, c.Param("id")).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Blankspot area not found")
+		return
+	}
+	if !checkIfMatch(c, blankspotArea.Version, func() { sendVersionConflict(c, blankspotArea.Version, blankspotArea) }) {
+		return
+	}
+
+	current := BlankspotAreaInput{
+		Name:        blankspotArea.Name,
+		Kelurahan:   blankspotArea.Kelurahan,
+		Coordinates: blankspotArea.Coordinates,
+		Type:        blankspotArea.Type,
+		Color:       blankspotArea.Color,
+	}
+	var patched BlankspotAreaInput
+	_, newData, ok := bindMergePatch(c, current, &patched)
+	if !ok {
+		return
+	}
+	if len(newData) > 0 && !applyBlankspotAreaUpdates(c, &blankspotArea, newData) {
+		return
+	}
+	helper.SetETag(c, blankspotArea.Version)
+	helper.SendSuccessResponse(c, http.StatusOK, "Blankspot area updated successfully", blankspotArea)
+}
+
+// applyBlankspotAreaUpdates writes updates to the blankspot area, responding 412 with its current
+// state if it was changed since it was loaded
+func applyBlankspotAreaUpdates(c *gin.Context, blankspotArea *models.BlankspotArea, updates map[string]interface{}) bool {
+	if err := updateVersioned(blankspotArea, blankspotArea.Version, updates); err != nil {
+		if errors.Is(err, errVersionConflict) {
+			database.DB.First(blankspotArea, blankspotArea.ID)
+			sendVersionConflict(c, blankspotArea.Version, blankspotArea)
+			return false
+		}
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to update blankspot area: "+err.Error())
+		return false
+	}
+	return true
+}
+
 // DeleteBlankspotArea handles deleting a blankspot area
 func DeleteBlankspotArea(c *gin.Context) {
 	var blankspotArea models.BlankspotArea
@@ -95,3 +149,25 @@ func DeleteBlankspotArea(c *gin.Context) {
 	database.DB.Unscoped().Delete(&blankspotArea)
 	helper.SendSuccessResponse(c, http.StatusOK, "Blankspot area permanently deleted", nil)
 }
+
+// GetBlankspotTowers lists the towers located inside a blankspot area
+func GetBlankspotTowers(c *gin.Context) {
+	var blankspotArea models.BlankspotArea
+	if err := database.DB.First(&blankspotArea, c.Param("id")).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Blankspot area not found")
+		return
+	}
+
+	ids, err := database.TowerIDsInBlankspot(blankspotArea)
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to search towers: "+err.Error())
+		return
+	}
+
+	towers := []models.Tower{}
+	database.DB.Preload("Providers").Where("id IN ?", ids).Find(&towers)
+	for i := range towers {
+		presentTower(c.Request.Context(), &towers[i])
+	}
+	helper.SendSuccessResponse(c, http.StatusOK, "Towers in blankspot area fetched successfully", towers)
+}
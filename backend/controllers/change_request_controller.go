@@ -0,0 +1,422 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"gorm.io/gorm"
+)
+
+// errChangeRequestReviewed is returned when a change request was reviewed in the meantime
+var errChangeRequestReviewed = errors.New("change request has already been reviewed")
+
+// operatorProviderID returns the provider of the current user if it is an operator account
+func operatorProviderID(c *gin.Context) (uint, bool) {
+	providerID, exists := c.Get("provider_id")
+	if !exists {
+		return 0, false
+	}
+	return providerID.(uint), true
+}
+
+// needsFourEyes reports whether relocations, dismantlements and deletions by the current user must
+// be approved by someone else before they take effect
+func needsFourEyes(c *gin.Context) bool {
+	_, isOperator := operatorProviderID(c)
+	return isOperator || config.Current.Approval.FourEyes
+}
+
+// authorizeTowerEdit checks that the current user may edit the tower, whose providers must be
+// loaded. Operator accounts may only edit towers of their own provider.
+func authorizeTowerEdit(c *gin.Context, tower *models.Tower) bool {
+	providerID, isOperator := operatorProviderID(c)
+	if !isOperator {
+		return true
+	}
+	for _, provider := range tower.Providers {
+		if provider.ID == providerID {
+			return true
+		}
+	}
+	helper.SendErrorResponse(c, http.StatusForbidden, "You can only edit towers of your own provider")
+	return false
+}
+
+// newPendingPhoto describes a stored upload so that it can be added to a tower later
+func newPendingPhoto(saved *savedPhoto, caption string, takenAt *time.Time, primary bool) *models.PendingPhoto {
+	pending := &models.PendingPhoto{
+		URL:          saved.URL,
+		MediumURL:    saved.MediumURL,
+		ThumbnailURL: saved.ThumbnailURL,
+		Caption:      caption,
+		TakenAt:      takenAt,
+		IsPrimary:    primary,
+	}
+	if saved.Metadata != nil {
+		pending.ExifLatitude = saved.Metadata.Latitude
+		pending.ExifLongitude = saved.Metadata.Longitude
+		pending.ExifTakenAt = saved.Metadata.TakenAt
+	}
+	return pending
+}
+
+// pendingPhotoSaved turns a pending photo back into the saved upload it was created from
+func pendingPhotoSaved(pending *models.PendingPhoto) *savedPhoto {
+	return &savedPhoto{
+		URL:          pending.URL,
+		MediumURL:    pending.MediumURL,
+		ThumbnailURL: pending.ThumbnailURL,
+		Metadata: &PhotoMetadata{
+			Latitude:  pending.ExifLatitude,
+			Longitude: pending.ExifLongitude,
+			TakenAt:   pending.ExifTakenAt,
+		},
+	}
+}
+
+// submitChangeRequest queues an edit of the tower for approval and responds with the change request.
+// newData must hold everything needed to apply the edit later.
+func submitChangeRequest(c *gin.Context, tower *models.Tower, changeType string, oldData, newData interface{}, photo *models.PendingPhoto) {
+	oldDataJSON, _ := json.Marshal(oldData)
+	newDataJSON, _ := json.Marshal(newData)
+
+	request := models.ChangeRequest{
+		TowerID:     tower.ID,
+		BaseVersion: tower.Version,
+		Type:        changeType,
+		Status:      models.ChangeRequestPending,
+		OldData:     string(oldDataJSON),
+		NewData:     string(newDataJSON),
+		Photo:       photo,
+		RequestedBy: c.MustGet("user_id").(uint),
+	}
+	if err := database.DB.Create(&request).Error; err != nil {
+		if photo != nil {
+			removePhotoFiles(c.Request.Context(), photo.URL, photo.MediumURL, photo.ThumbnailURL)
+		}
+		helper.Logger(c).Error("Failed to create change request", "tower_id", tower.ID, "type", changeType, "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to submit change for approval")
+		return
+	}
+	helper.Logger(c).Info("Change request submitted", "change_request_id", request.ID, "tower_id", tower.ID, "type", changeType)
+
+	presentChangeRequest(c.Request.Context(), &request)
+	helper.SendSuccessResponse(c, http.StatusAccepted, "Change submitted for approval", request)
+}
+
+// applyChangeRequest carries out an approved change request on behalf of the user who submitted it.
+// It fails with errVersionConflict unless the tower is at the given version; 0 skips the check.
+func applyChangeRequest(c *gin.Context, request *models.ChangeRequest, version uint) error {
+	var tower models.Tower
+	if err := database.DB.Preload("Providers").First(&tower, request.TowerID).Error; err != nil {
+		return fmt.Errorf("failed to load tower %d: %w", request.TowerID, err)
+	}
+	if version != 0 && tower.Version != version {
+		return errVersionConflict
+	}
+
+	switch request.Type {
+	case "DetailsUpdate":
+		var change towerDetailsChange
+		if err := json.Unmarshal([]byte(request.NewData), &change); err != nil {
+			return err
+		}
+		return updateTowerDetails(c, &tower, change, request.Photo, request.RequestedBy)
+	case "Relocation":
+		var input RelocateTowerInput
+		if err := json.Unmarshal([]byte(request.NewData), &input); err != nil {
+			return err
+		}
+		return relocateTower(c, &tower, input, request.RequestedBy)
+	case "OwnershipChange":
+		var input struct {
+			NewProviderID uint `json:"new_provider_id"`
+		}
+		if err := json.Unmarshal([]byte(request.NewData), &input); err != nil {
+			return err
+		}
+		var newProvider models.Provider
+		if err := database.DB.First(&newProvider, input.NewProviderID).Error; err != nil {
+			return fmt.Errorf("failed to load provider %d: %w", input.NewProviderID, err)
+		}
+		return changeTowerOwnership(c, &tower, &newProvider, request.RequestedBy)
+	case "PhotoUpdate":
+		if request.Photo == nil {
+			return errors.New("change request has no photo")
+		}
+		_, err := uploadTowerPhoto(c, &tower, request.Photo, request.RequestedBy)
+		return err
+	case "Dismantled":
+		return dismantleTower(c, &tower, request.RequestedBy)
+	default:
+		return fmt.Errorf("unknown change request type %q", request.Type)
+	}
+}
+
+// markChangeRequestReviewed moves a pending change request to status, failing with
+// errChangeRequestReviewed if someone else reviewed it first
+func markChangeRequestReviewed(request *models.ChangeRequest, status string, reviewerID uint, reason string) error {
+	now := time.Now()
+	result := database.DB.Model(&models.ChangeRequest{}).
+		Where("id = ? AND status = ?", request.ID, models.ChangeRequestPending).
+		Updates(map[string]interface{}{"status": status, "reviewed_by": reviewerID, "reviewed_at": now, "rejection_reason": reason})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return errChangeRequestReviewed
+	}
+
+	request.Status = status
+	request.ReviewedBy = &reviewerID
+	request.ReviewedAt = &now
+	request.RejectionReason = reason
+	return nil
+}
+
+// changeRequestBase returns the tower version a change request may be applied to: the version it
+// was made against, or the current one if the reviewer confirms it with the tower's ETag in
+// If-Match after seeing what changed in the meantime. Otherwise it responds 409 with those changes.
+func changeRequestBase(c *gin.Context, request *models.ChangeRequest) (uint, bool) {
+	if request.BaseVersion == 0 {
+		return 0, true // Submitted before versions were recorded
+	}
+	var tower models.Tower
+	if err := database.DB.Select("id", "version").First(&tower, request.TowerID).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
+		return 0, false
+	}
+	if tower.Version == request.BaseVersion {
+		return tower.Version, true
+	}
+	if present, matches := helper.IfMatch(c, tower.Version); present && matches {
+		helper.Logger(c).Info("Approving change request against a newer tower version", "change_request_id", request.ID,
+			"base_version", request.BaseVersion, "current_version", tower.Version)
+		return tower.Version, true
+	}
+	sendChangeRequestConflict(c, request)
+	return 0, false
+}
+
+// sendChangeRequestConflict responds 409 with the current tower and its ETag, and the values the
+// change request replaces that were changed on the tower since it was submitted
+func sendChangeRequestConflict(c *gin.Context, request *models.ChangeRequest) {
+	var tower models.Tower
+	if err := database.DB.Preload("Providers").Preload("Photos", orderedPhotos).First(&tower, request.TowerID).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
+		return
+	}
+
+	var submitted map[string]interface{}
+	json.Unmarshal([]byte(request.OldData), &submitted)
+	current := changeRequestFields(&tower)
+	currentSubmitted := gin.H{}
+	for name := range submitted {
+		currentSubmitted[name] = current[name]
+	}
+	before, now := changedFields(submitted, currentSubmitted)
+
+	helper.Logger(c).Info("Refused change request based on a stale tower version", "change_request_id", request.ID,
+		"base_version", request.BaseVersion, "current_version", tower.Version)
+	presentTower(c.Request.Context(), &tower)
+	helper.SetETag(c, tower.Version)
+	helper.SendErrorResponseWithData(c, http.StatusConflict,
+		"The tower was changed since the request was submitted. Review the changes, then approve with the tower's ETag in If-Match to apply it anyway",
+		gin.H{
+			"base_version":    request.BaseVersion,
+			"current_version": tower.Version,
+			"changes":         gin.H{"submitted_against": before, "current": now},
+			"tower":           tower,
+		})
+}
+
+// changeRequestFields returns the tower values change requests record in OldData, as JSON values
+func changeRequestFields(tower *models.Tower) map[string]interface{} {
+	fields := towerDetailsData(tower)
+	fields["latitude"] = tower.Latitude
+	fields["longitude"] = tower.Longitude
+	fields["status"] = tower.Status
+	fields["providers"] = getProviderNames(tower.Providers)
+	return jsonObject(fields)
+}
+
+// findPendingChangeRequest loads the pending change request identified by the :id parameter
+func findPendingChangeRequest(c *gin.Context) (*models.ChangeRequest, bool) {
+	var request models.ChangeRequest
+	if err := database.DB.First(&request, c.Param("id")).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Change request not found")
+		return nil, false
+	}
+	if request.Status != models.ChangeRequestPending {
+		helper.SendErrorResponse(c, http.StatusConflict, "Change request has already been "+request.Status)
+		return nil, false
+	}
+	return &request, true
+}
+
+// authorizeReview checks that the current user may review the change request. Nobody reviews
+// their own requests, and in four-eyes mode only approvers review at all.
+func authorizeReview(c *gin.Context, request *models.ChangeRequest) bool {
+	reviewerID := c.MustGet("user_id").(uint)
+	if request.RequestedBy == reviewerID {
+		helper.SendErrorResponse(c, http.StatusForbidden, "You cannot review your own change request")
+		return false
+	}
+	if !config.Current.Approval.FourEyes {
+		return true
+	}
+
+	var reviewer models.User
+	if err := database.DB.Select("id", "is_approver").First(&reviewer, reviewerID).Error; err != nil {
+		helper.Logger(c).Error("Failed to load reviewer", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to load user")
+		return false
+	}
+	if !reviewer.IsApprover {
+		helper.SendErrorResponse(c, http.StatusForbidden, "Reviewing change requests requires approver rights")
+		return false
+	}
+	return true
+}
+
+// GetChangeRequests lists change requests, newest first, optionally filtered by the status and
+// tower_id query parameters. Operator accounts only see their own requests.
+func GetChangeRequests(c *gin.Context) {
+	query := database.DB.Order("id DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if towerID := c.Query("tower_id"); towerID != "" {
+		query = query.Where("tower_id = ?", towerID)
+	}
+	if _, isOperator := operatorProviderID(c); isOperator {
+		query = query.Where("requested_by = ?", c.MustGet("user_id").(uint))
+	}
+
+	var requests []models.ChangeRequest
+	if err := query.Find(&requests).Error; err != nil {
+		helper.Logger(c).Error("Failed to fetch change requests", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch change requests")
+		return
+	}
+	for i := range requests {
+		presentChangeRequest(c.Request.Context(), &requests[i])
+	}
+	helper.SendSuccessResponse(c, http.StatusOK, "Change requests fetched successfully", requests)
+}
+
+// GetChangeRequestQueue lists the pending change requests the current user may review, oldest first
+func GetChangeRequestQueue(c *gin.Context) {
+	var requests []models.ChangeRequest
+	err := database.DB.Where("status = ? AND requested_by <> ?", models.ChangeRequestPending, c.MustGet("user_id").(uint)).
+		Order("id").Find(&requests).Error
+	if err != nil {
+		helper.Logger(c).Error("Failed to fetch change request queue", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch change requests")
+		return
+	}
+	for i := range requests {
+		presentChangeRequest(c.Request.Context(), &requests[i])
+	}
+	helper.SendSuccessResponse(c, http.StatusOK, "Change requests fetched successfully", requests)
+}
+
+// GetChangeRequest returns a single change request. Operator accounts only see their own requests.
+func GetChangeRequest(c *gin.Context) {
+	var request models.ChangeRequest
+	err := database.DB.First(&request, c.Param("id")).Error
+	if _, isOperator := operatorProviderID(c); err == nil && isOperator && request.RequestedBy != c.MustGet("user_id").(uint) {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Change request not found")
+		return
+	}
+	presentChangeRequest(c.Request.Context(), &request)
+	helper.SendSuccessResponse(c, http.StatusOK, "Change request fetched successfully", request)
+}
+
+// ApproveChangeRequest applies a pending change request. If the tower was changed since the
+// request was submitted, the approval must confirm the current version with If-Match.
+func ApproveChangeRequest(c *gin.Context) {
+	request, ok := findPendingChangeRequest(c)
+	if !ok || !authorizeReview(c, request) {
+		return
+	}
+	version, ok := changeRequestBase(c, request)
+	if !ok {
+		return
+	}
+
+	// Claiming the request first makes sure it is applied only once
+	reviewerID := c.MustGet("user_id").(uint)
+	if err := markChangeRequestReviewed(request, models.ChangeRequestApproved, reviewerID, ""); err != nil {
+		if errors.Is(err, errChangeRequestReviewed) {
+			helper.SendErrorResponse(c, http.StatusConflict, "Change request has already been reviewed")
+			return
+		}
+		helper.Logger(c).Error("Failed to approve change request", "change_request_id", request.ID, "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to approve change request")
+		return
+	}
+
+	if err := applyChangeRequest(c, request, version); err != nil {
+		revert := map[string]interface{}{"status": models.ChangeRequestPending, "reviewed_by": nil, "reviewed_at": nil}
+		if err := database.DB.Model(request).Updates(revert).Error; err != nil {
+			helper.Logger(c).Error("Failed to reopen change request", "change_request_id", request.ID, "error", err)
+		}
+		if errors.Is(err, errVersionConflict) {
+			// The tower was changed while the request was being approved
+			sendChangeRequestConflict(c, request)
+			return
+		}
+		helper.Logger(c).Error("Failed to apply change request", "change_request_id", request.ID, "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to apply change request: "+err.Error())
+		return
+	}
+	helper.Logger(c).Info("Change request approved", "change_request_id", request.ID, "tower_id", request.TowerID, "type", request.Type)
+
+	helper.SendSuccessResponse(c, http.StatusOK, "Change request approved", request)
+}
+
+type RejectChangeRequestInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// RejectChangeRequest discards a pending change request with the reason given, deleting the photo uploaded with it
+func RejectChangeRequest(c *gin.Context) {
+	var input RejectChangeRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	request, ok := findPendingChangeRequest(c)
+	if !ok || !authorizeReview(c, request) {
+		return
+	}
+
+	if err := markChangeRequestReviewed(request, models.ChangeRequestRejected, c.MustGet("user_id").(uint), input.Reason); err != nil {
+		if errors.Is(err, errChangeRequestReviewed) {
+			helper.SendErrorResponse(c, http.StatusConflict, "Change request has already been reviewed")
+			return
+		}
+		helper.Logger(c).Error("Failed to reject change request", "change_request_id", request.ID, "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to reject change request")
+		return
+	}
+	if request.Photo != nil {
+		removePhotoFiles(c.Request.Context(), request.Photo.URL, request.Photo.MediumURL, request.Photo.ThumbnailURL)
+	}
+	helper.Logger(c).Info("Change request rejected", "change_request_id", request.ID, "tower_id", request.TowerID, "type", request.Type)
+
+	helper.SendSuccessResponse(c, http.StatusOK, "Change request rejected", request)
+}
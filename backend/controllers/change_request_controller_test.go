@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+// setupDismantleRequest creates a tower and a pending request by operator to dismantle it, made
+// against the current version of the tower
+func setupDismantleRequest(t *testing.T) (tower models.Tower, request models.ChangeRequest, reviewer models.User) {
+	testutil.Setup(t, nil)
+	operator := createUser(t, "operator@example.com", false)
+	reviewer = createUser(t, "reviewer@example.com", true)
+
+	tower = models.Tower{Kelurahan: "Paruga", Status: "active"}
+	require.NoError(t, database.DB.Create(&tower).Error)
+	request = models.ChangeRequest{
+		TowerID:     tower.ID,
+		BaseVersion: tower.Version,
+		Type:        "Dismantled",
+		Status:      models.ChangeRequestPending,
+		OldData:     `{"status":"active"}`,
+		NewData:     `{"status":"dismantled"}`,
+		RequestedBy: operator.ID,
+	}
+	require.NoError(t, database.DB.Create(&request).Error)
+	return tower, request, reviewer
+}
+
+// approve approves the change request as reviewer, with ifMatch in If-Match unless empty
+func approve(reviewer models.User, request models.ChangeRequest, ifMatch string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.POST("/api/change-requests/:id/approve", signedInAs(reviewer.ID), ApproveChangeRequest)
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/change-requests/%d/approve", request.ID), nil)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// changeRequestState returns the status of the change request and of its tower
+func changeRequestState(t *testing.T, request models.ChangeRequest) (requestStatus, towerStatus string) {
+	var stored models.ChangeRequest
+	require.NoError(t, database.DB.First(&stored, request.ID).Error)
+	var tower models.Tower
+	require.NoError(t, database.DB.First(&tower, request.TowerID).Error)
+	return stored.Status, tower.Status
+}
+
+func TestApproveChangeRequestAtBaseVersion(t *testing.T) {
+	_, request, reviewer := setupDismantleRequest(t)
+
+	w := approve(reviewer, request, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	requestStatus, towerStatus := changeRequestState(t, request)
+	assert.Equal(t, models.ChangeRequestApproved, requestStatus)
+	assert.Equal(t, "dismantled", towerStatus)
+}
+
+func TestApproveChangeRequestRefusesStaleBase(t *testing.T) {
+	tower, request, reviewer := setupDismantleRequest(t)
+	require.NoError(t, updateVersioned(&tower, tower.Version, map[string]interface{}{"Status": "inactive"}))
+
+	w := approve(reviewer, request, "")
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Equal(t, helper.ETag(request.BaseVersion+1), w.Header().Get("ETag"))
+
+	var body struct {
+		Data struct {
+			BaseVersion    uint `json:"base_version"`
+			CurrentVersion uint `json:"current_version"`
+			Changes        struct {
+				SubmittedAgainst map[string]interface{} `json:"submitted_against"`
+				Current          map[string]interface{} `json:"current"`
+			} `json:"changes"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, request.BaseVersion, body.Data.BaseVersion)
+	assert.Equal(t, request.BaseVersion+1, body.Data.CurrentVersion)
+	assert.Equal(t, map[string]interface{}{"status": "active"}, body.Data.Changes.SubmittedAgainst)
+	assert.Equal(t, map[string]interface{}{"status": "inactive"}, body.Data.Changes.Current)
+
+	requestStatus, towerStatus := changeRequestState(t, request)
+	assert.Equal(t, models.ChangeRequestPending, requestStatus)
+	assert.Equal(t, "inactive", towerStatus)
+
+	w = approve(reviewer, request, helper.ETag(request.BaseVersion))
+	assert.Equal(t, http.StatusConflict, w.Code, "If-Match with the base version")
+
+	w = approve(reviewer, request, w.Header().Get("ETag"))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	requestStatus, towerStatus = changeRequestState(t, request)
+	assert.Equal(t, models.ChangeRequestApproved, requestStatus)
+	assert.Equal(t, "dismantled", towerStatus)
+}
+
+func TestApproveChangeRequestWithoutBaseVersion(t *testing.T) {
+	tower, request, reviewer := setupDismantleRequest(t)
+	require.NoError(t, database.DB.Model(&request).Update("base_version", 0).Error)
+	request.BaseVersion = 0
+	require.NoError(t, updateVersioned(&tower, tower.Version, map[string]interface{}{"Status": "inactive"}))
+
+	w := approve(reviewer, request, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	_, towerStatus := changeRequestState(t, request)
+	assert.Equal(t, "dismantled", towerStatus)
+}
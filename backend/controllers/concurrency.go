@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"gorm.io/gorm"
+)
+
+// errVersionConflict is returned by versioned writes when the row was changed since it was loaded
+var errVersionConflict = errors.New("resource was modified concurrently")
+
+// checkIfMatch enforces the If-Match precondition of a write to a resource at the given version.
+// Writes without the header fail with 428, writes based on a stale ETag call conflict, which sends
+// the current state of the resource.
+func checkIfMatch(c *gin.Context, version uint, conflict func()) bool {
+	present, matches := helper.IfMatch(c, version)
+	if !present {
+		helper.SendErrorResponse(c, http.StatusPreconditionRequired, "The If-Match header is required, send the ETag of the resource as last fetched")
+		return false
+	}
+	if !matches {
+		conflict()
+		return false
+	}
+	return true
+}
+
+// sendVersionConflict responds 412 with the current state of the resource and its ETag
+func sendVersionConflict(c *gin.Context, version uint, current interface{}) {
+	helper.Logger(c).Info("Rejected write based on a stale version", "path", c.FullPath(), "current_version", version)
+	helper.SetETag(c, version)
+	helper.SendErrorResponseWithData(c, http.StatusPreconditionFailed, "The resource was modified since it was fetched, review the current state and retry", current)
+}
+
+// sendTowerConflict responds 412 with the current state of the tower as GetTower returns it
+func sendTowerConflict(c *gin.Context, towerID uint) {
+	var tower models.Tower
+	if err := database.DB.Preload("Providers").Preload("Photos", orderedPhotos).First(&tower, towerID).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
+		return
+	}
+	presentTower(c.Request.Context(), &tower)
+	sendVersionConflict(c, tower.Version, tower)
+}
+
+// saveTower writes the columns of the tower if its row is still at the version the tower was
+// loaded with, and increments the version. Stale writes fail with errVersionConflict.
+func saveTower(db *gorm.DB, tower *models.Tower) error {
+	expected := tower.Version
+	tower.Version++
+	result := db.Model(tower).Where("version = ?", expected).
+		Select("*").Omit("Providers", "Photos", "CreatedAt").
+		Updates(tower)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = errVersionConflict
+	}
+	if result.Error != nil {
+		tower.Version = expected
+		return result.Error
+	}
+	return nil
+}
+
+// touchTower increments the version of a tower whose photos changed if it is still at the given
+// version. Stale writes fail with errVersionConflict.
+func touchTower(tx *gorm.DB, towerID, version uint) error {
+	result := tx.Model(&models.Tower{}).Where("id = ? AND version = ?", towerID, version).UpdateColumn("version", gorm.Expr("version + 1"))
+	if result.Error == nil && result.RowsAffected == 0 {
+		return errVersionConflict
+	}
+	return result.Error
+}
+
+// loadTowerVersion loads the version of the tower a photo write goes to and checks it against
+// If-Match, responding 404, 428 or 412 otherwise
+func loadTowerVersion(c *gin.Context, towerID interface{}) (*models.Tower, bool) {
+	var tower models.Tower
+	if err := database.DB.Select("id", "version").First(&tower, towerID).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
+		return nil, false
+	}
+	if !checkIfMatch(c, tower.Version, func() { sendTowerConflict(c, tower.ID) }) {
+		return nil, false
+	}
+	return &tower, true
+}
+
+// updateVersioned applies updates to the loaded record model if its row is still at the given
+// version, and increments the version. Stale writes fail with errVersionConflict.
+func updateVersioned(model interface{}, version uint, updates map[string]interface{}) error {
+	updates["Version"] = version + 1
+	result := database.DB.Model(model).Where("version = ?", version).Updates(updates)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return errVersionConflict
+	}
+	return result.Error
+}
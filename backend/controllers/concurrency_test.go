@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+// sendJSON sends body with the given content type to router, with ifMatch in If-Match unless empty
+func sendJSON(router *gin.Engine, method, path, contentType, body, ifMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// versionOf returns the version in the data of a response
+func versionOf(t *testing.T, w *httptest.ResponseRecorder) uint {
+	var body struct {
+		Data struct {
+			Version uint `json:"version"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body.Data.Version
+}
+
+// testIfMatch checks that writes to path require the ETag of the resource at version: writes without
+// If-Match fail with 428, stale ones with 412 and the current state, and current ones succeed
+// with the new ETag
+func testIfMatch(t *testing.T, router *gin.Engine, method, path, contentType, body string, version uint) {
+	w := sendJSON(router, method, path, contentType, body, "")
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code, "without If-Match")
+
+	w = sendJSON(router, method, path, contentType, body, helper.ETag(version+1))
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code, "stale If-Match")
+	assert.Equal(t, helper.ETag(version), w.Header().Get("ETag"))
+	assert.Equal(t, version, versionOf(t, w), "412 responses carry the current state")
+
+	w = sendJSON(router, method, path, contentType, body, helper.ETag(version))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, helper.ETag(version+1), w.Header().Get("ETag"))
+	assert.Equal(t, version+1, versionOf(t, w))
+
+	w = sendJSON(router, method, path, contentType, body, helper.ETag(version))
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code, "replaying the same If-Match")
+}
+
+func TestProviderWritesRequireIfMatch(t *testing.T) {
+	testutil.Setup(t, nil)
+	router := gin.New()
+	router.PUT("/api/providers/:id", UpdateProvider)
+	router.PATCH("/api/providers/:id", PatchProvider)
+
+	provider := models.Provider{Name: "Operator A"}
+	require.NoError(t, database.DB.Create(&provider).Error)
+	path := fmt.Sprintf("/api/providers/%d", provider.ID)
+
+	t.Run("PUT", func(t *testing.T) {
+		testIfMatch(t, router, http.MethodPut, path, "application/json", `{"name": "Operator B", "address": "Jl. Sudirman"}`, 1)
+	})
+	t.Run("PATCH", func(t *testing.T) {
+		testIfMatch(t, router, http.MethodPatch, path, mergePatchContentType, `{"address": "Jl. Soekarno-Hatta"}`, 2)
+	})
+
+	require.NoError(t, database.DB.First(&provider, provider.ID).Error)
+	assert.Equal(t, "Operator B", provider.Name)
+	assert.Equal(t, "Jl. Soekarno-Hatta", provider.Address)
+}
+
+func TestBlankspotAreaWritesRequireIfMatch(t *testing.T) {
+	testutil.Setup(t, nil)
+	router := gin.New()
+	router.PUT("/api/blankspots/:id", UpdateBlankspotArea)
+	router.PATCH("/api/blankspots/:id", PatchBlankspotArea)
+
+	area := models.BlankspotArea{Name: "Paruga", Kelurahan: "Paruga", Coordinates: "[[-8.45, 118.72]]", Type: "Blankspot", Color: "#FF0000"}
+	require.NoError(t, database.DB.Create(&area).Error)
+	path := fmt.Sprintf("/api/blankspots/%d", area.ID)
+
+	t.Run("PUT", func(t *testing.T) {
+		body := `{"name": "Paruga", "kelurahan": "Paruga", "coordinates": "[[-8.45, 118.72]]", "type": "Weak Signal", "color": "#FFFF00"}`
+		testIfMatch(t, router, http.MethodPut, path, "application/json", body, 1)
+	})
+	t.Run("PATCH", func(t *testing.T) {
+		testIfMatch(t, router, http.MethodPatch, path, mergePatchContentType, `{"name": "Paruga Selatan"}`, 2)
+	})
+
+	require.NoError(t, database.DB.First(&area, area.ID).Error)
+	assert.Equal(t, "Paruga Selatan", area.Name)
+	assert.Equal(t, "Weak Signal", area.Type)
+}
+
+func TestTowerPhotoWritesRequireIfMatch(t *testing.T) {
+	testutil.Setup(t, nil)
+	store := useMemoryStorage(t)
+	staff := createUser(t, "staff@example.com", false)
+	tower, photo := createTowerWithPhoto(t, store)
+
+	tests := []struct {
+		name, method, pattern, path, body string
+		handler                           gin.HandlerFunc
+	}{
+		{"update", http.MethodPut, "/api/towers/:id/photos/:photo_id", fmt.Sprintf("/api/towers/%d/photos/%d", tower.ID, photo.ID), `{"caption": "North side"}`, UpdateTowerPhoto},
+		{"reorder", http.MethodPut, "/api/towers/:id/photos/order", fmt.Sprintf("/api/towers/%d/photos/order", tower.ID), fmt.Sprintf(`{"photo_ids": [%d]}`, photo.ID), ReorderTowerPhotos},
+		{"delete", http.MethodDelete, "/api/towers/:id/photos/:photo_id", fmt.Sprintf("/api/towers/%d/photos/%d", tower.ID, photo.ID), "", DeleteTowerPhoto},
+	}
+	version := tower.Version
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Handle(tt.method, tt.pattern, signedInAs(staff.ID), tt.handler)
+
+			w := sendJSON(router, tt.method, tt.path, "application/json", tt.body, "")
+			assert.Equal(t, http.StatusPreconditionRequired, w.Code, "without If-Match")
+			w = sendJSON(router, tt.method, tt.path, "application/json", tt.body, helper.ETag(version+1))
+			assert.Equal(t, http.StatusPreconditionFailed, w.Code, "stale If-Match")
+			assert.Equal(t, helper.ETag(version), w.Header().Get("ETag"))
+
+			w = sendJSON(router, tt.method, tt.path, "application/json", tt.body, helper.ETag(version))
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			version++
+			assert.Equal(t, helper.ETag(version), w.Header().Get("ETag"))
+
+			var current models.Tower
+			require.NoError(t, database.DB.First(&current, tower.ID).Error)
+			assert.Equal(t, version, current.Version, "photo changes increment the tower version once")
+		})
+	}
+}
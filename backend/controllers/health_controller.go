@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/logging"
+	"github.com/user/tower-tracker-bima/backend/storage"
+	"github.com/user/tower-tracker-bima/backend/version"
+)
+
+// readinessTimeout bounds each readiness check, so that a hanging dependency fails the probe instead of blocking it
+const readinessTimeout = 2 * time.Second
+
+// readinessProbeKey is written to and deleted from upload storage to check that it is writable
+const readinessProbeKey = ".readyz-probe"
+
+// Healthz reports that the process is alive. It checks no dependencies.
+func Healthz(c *gin.Context) {
+	helper.SendSuccessResponse(c, http.StatusOK, "OK", nil)
+}
+
+// Readyz reports whether the server can handle requests: the database answers, upload storage
+// is writable and the schema is current. It responds with 503 and the failed checks otherwise.
+func Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]string{}
+	ready := true
+	check := func(name string, err error) {
+		if err != nil {
+			checks[name] = err.Error()
+			ready = false
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	sqlDB, err := database.DB.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	check("database", err)
+	if err == nil {
+		check("migrations", database.CheckSchemaCurrent(database.DB.WithContext(ctx)))
+	}
+
+	err = storage.Default.Put(ctx, readinessProbeKey, bytes.NewReader(nil), 0, "text/plain")
+	if err == nil {
+		err = storage.Default.Delete(ctx, readinessProbeKey)
+	}
+	check("uploads", err)
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, helper.Response{Status: "error", Message: "Not ready", Data: checks, RequestID: c.GetString(logging.RequestIDKey)})
+		return
+	}
+	helper.SendSuccessResponse(c, http.StatusOK, "Ready", checks)
+}
+
+// Version reports the commit, build time and Go version of the running build
+func Version(c *gin.Context) {
+	helper.SendSuccessResponse(c, http.StatusOK, "Build information retrieved successfully", version.Get())
+}
@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/helper"
+)
+
+// GetJWKS publishes the public keys user tokens are signed with, so that other services can verify
+// them. It is a plain JWK set rather than the usual response envelope, as clients expect.
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, helper.JWTKeys.JWKS())
+}
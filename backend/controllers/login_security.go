@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"gorm.io/gorm"
+)
+
+// maxUserAgentLength bounds the user agent stored with login events
+const maxUserAgentLength = 512
+
+// lockoutDuration returns how long an account stays locked after failures consecutive failed logins.
+// Accounts are not locked below the threshold; from there the lockout doubles with every failure.
+func lockoutDuration(failures int) time.Duration {
+	cfg := config.Current.Auth
+	if failures < cfg.LockoutThreshold {
+		return 0
+	}
+	duration := cfg.LockoutBase
+	for i := cfg.LockoutThreshold; i < failures && duration < cfg.LockoutMax; i++ {
+		duration *= 2
+	}
+	return min(duration, cfg.LockoutMax)
+}
+
+// recordLoginEvent adds a login attempt to the login history
+func recordLoginEvent(c *gin.Context, userID *uint, email string, success bool, failureReason string) {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	event := models.LoginEvent{
+		UserID:        userID,
+		Email:         email,
+		IP:            c.ClientIP(),
+		UserAgent:     userAgent,
+		Success:       success,
+		FailureReason: failureReason,
+	}
+	if err := database.DB.Create(&event).Error; err != nil {
+		helper.Logger(c).Error("Failed to record login event", "email", email, "error", err)
+	}
+}
+
+// registerFailedLogin counts a failed login against user and locks the account once there were too many
+func registerFailedLogin(c *gin.Context, user *models.User) {
+	err := database.DB.Model(user).UpdateColumn("failed_login_attempts", gorm.Expr("failed_login_attempts + 1")).Error
+	if err == nil {
+		err = database.DB.Model(user).Select("failed_login_attempts").First(user).Error
+	}
+	if err != nil {
+		helper.Logger(c).Error("Failed to count failed login", "target_user_id", user.ID, "error", err)
+		return
+	}
+
+	duration := lockoutDuration(user.FailedLoginAttempts)
+	if duration == 0 {
+		return
+	}
+	lockedUntil := time.Now().Add(duration)
+	if err := database.DB.Model(user).UpdateColumn("locked_until", lockedUntil).Error; err != nil {
+		helper.Logger(c).Error("Failed to lock account", "target_user_id", user.ID, "error", err)
+		return
+	}
+	helper.Logger(c).Warn("Account locked after failed logins", "target_user_id", user.ID,
+		"failed_attempts", user.FailedLoginAttempts, "locked_until", lockedUntil)
+}
+
+// resetFailedLogins clears the failed login counter and lockout of user after a successful login
+func resetFailedLogins(c *gin.Context, user *models.User) {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return
+	}
+	err := database.DB.Model(user).UpdateColumns(map[string]interface{}{"failed_login_attempts": 0, "locked_until": nil}).Error
+	if err != nil {
+		helper.Logger(c).Error("Failed to reset failed logins", "target_user_id", user.ID, "error", err)
+	}
+}
+
+// checkLoginAnomalies raises a security alert when user has successfully logged in from too many
+// distinct IP addresses within the alert window. At most one alert per window is raised per account.
+func checkLoginAnomalies(c *gin.Context, user *models.User) {
+	cfg := config.Current.Auth
+	since := time.Now().Add(-cfg.AlertWindow)
+
+	var distinctIPs int64
+	err := database.DB.Model(&models.LoginEvent{}).
+		Where("user_id = ? AND success = ? AND created_at >= ?", user.ID, true, since).
+		Distinct("ip").Count(&distinctIPs).Error
+	if err != nil {
+		helper.Logger(c).Error("Failed to check login anomalies", "target_user_id", user.ID, "error", err)
+		return
+	}
+	if distinctIPs < int64(cfg.AlertDistinctIPs) {
+		return
+	}
+
+	var recentAlerts int64
+	database.DB.Model(&models.SecurityAlert{}).
+		Where("user_id = ? AND type = ? AND created_at >= ?", user.ID, models.SecurityAlertManyIPs, since).
+		Count(&recentAlerts)
+	if recentAlerts > 0 {
+		return
+	}
+
+	alert := models.SecurityAlert{
+		UserID:  user.ID,
+		Type:    models.SecurityAlertManyIPs,
+		Message: fmt.Sprintf("%s logged in from %d different IP addresses within %s", user.Email, distinctIPs, cfg.AlertWindow),
+	}
+	if err := database.DB.Create(&alert).Error; err != nil {
+		helper.Logger(c).Error("Failed to create security alert", "target_user_id", user.ID, "error", err)
+		return
+	}
+	helper.Logger(c).Warn("Security alert raised", "alert_id", alert.ID, "type", alert.Type, "target_user_id", user.ID, "distinct_ips", distinctIPs)
+}
+
+// GetSessions lists the recent logins of the current user, newest first. Failed attempts are
+// included so that users can spot someone guessing their password.
+func GetSessions(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "limit must be a number between 1 and 200")
+		return
+	}
+
+	userID := c.MustGet("user_id").(uint)
+	var events []models.LoginEvent
+	if err := database.DB.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&events).Error; err != nil {
+		helper.Logger(c).Error("Failed to fetch login history", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch login history")
+		return
+	}
+
+	helper.SendSuccessResponse(c, http.StatusOK, "Login history fetched successfully", events)
+}
+
+// GetSecurityAlerts lists security alerts, newest first. ?acknowledged=false only returns open alerts.
+func GetSecurityAlerts(c *gin.Context) {
+	query := database.DB.Order("created_at DESC, id DESC")
+	switch c.Query("acknowledged") {
+	case "":
+	case "true":
+		query = query.Where("acknowledged_at IS NOT NULL")
+	case "false":
+		query = query.Where("acknowledged_at IS NULL")
+	default:
+		helper.SendErrorResponse(c, http.StatusBadRequest, "acknowledged must be true or false")
+		return
+	}
+
+	var alerts []models.SecurityAlert
+	if err := query.Find(&alerts).Error; err != nil {
+		helper.Logger(c).Error("Failed to fetch security alerts", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch security alerts")
+		return
+	}
+
+	helper.SendSuccessResponse(c, http.StatusOK, "Security alerts fetched successfully", alerts)
+}
+
+// AcknowledgeSecurityAlert marks a security alert as handled by the current user
+func AcknowledgeSecurityAlert(c *gin.Context) {
+	var alert models.SecurityAlert
+	if err := database.DB.First(&alert, c.Param("id")).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Security alert not found")
+		return
+	}
+
+	if alert.AcknowledgedAt == nil {
+		userID := c.MustGet("user_id").(uint)
+		now := time.Now()
+		alert.AcknowledgedAt = &now
+		alert.AcknowledgedBy = &userID
+		if err := database.DB.Save(&alert).Error; err != nil {
+			helper.Logger(c).Error("Failed to acknowledge security alert", "alert_id", alert.ID, "error", err)
+			helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to acknowledge security alert")
+			return
+		}
+	}
+
+	helper.SendSuccessResponse(c, http.StatusOK, "Security alert acknowledged", alert)
+}
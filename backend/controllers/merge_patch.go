@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/user/tower-tracker-bima/backend/helper"
+)
+
+// mergePatchContentType is the media type of JSON Merge Patch documents (RFC 7396)
+const mergePatchContentType = "application/merge-patch+json"
+
+// bindMergePatch reads a JSON Merge Patch from the request body and applies it to current, a struct
+// holding the patchable fields of a resource. The result is decoded into patched, a pointer to a
+// struct of the same type, and validated by its binding tags. Members set to null reset a field
+// to its zero value; patches naming fields outside current are refused. It returns the old and
+// new values of the fields that changed, or sends an error response and returns false.
+func bindMergePatch(c *gin.Context, current, patched interface{}) (oldData, newData gin.H, ok bool) {
+	// Plain JSON is accepted too, as merge patches of flat resources look the same
+	if contentType := c.ContentType(); contentType != mergePatchContentType && contentType != binding.MIMEJSON {
+		c.Header("Accept-Patch", mergePatchContentType)
+		helper.SendErrorResponse(c, http.StatusUnsupportedMediaType, "PATCH requests must have a "+mergePatchContentType+" body")
+		return nil, nil, false
+	}
+
+	var patch interface{}
+	if err := json.NewDecoder(c.Request.Body).Decode(&patch); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid merge patch: "+err.Error())
+		return nil, nil, false
+	}
+	patchObject, isObject := patch.(map[string]interface{})
+	if !isObject {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid merge patch: the body must be a JSON object")
+		return nil, nil, false
+	}
+
+	target := jsonObject(current)
+	var unknown []string
+	for name := range patchObject {
+		if _, patchable := target[name]; !patchable {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		patchable := make([]string, 0, len(target))
+		for name := range target {
+			patchable = append(patchable, name)
+		}
+		sort.Strings(unknown)
+		sort.Strings(patchable)
+		helper.SendErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Cannot patch %s, patchable fields are %s",
+			strings.Join(unknown, ", "), strings.Join(patchable, ", ")))
+		return nil, nil, false
+	}
+
+	merged, _ := json.Marshal(helper.MergePatch(target, patchObject))
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patched); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid merge patch: "+err.Error())
+		return nil, nil, false
+	}
+	if err := binding.Validator.ValidateStruct(patched); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+
+	oldData, newData = changedFields(current, patched)
+	return oldData, newData, true
+}
+
+// changedFields compares two values by their JSON members and returns the old and new values of
+// the members that differ
+func changedFields(oldValue, newValue interface{}) (oldData, newData gin.H) {
+	oldObject, newObject := jsonObject(oldValue), jsonObject(newValue)
+	oldData, newData = gin.H{}, gin.H{}
+	for name, value := range newObject {
+		if !reflect.DeepEqual(oldObject[name], value) {
+			oldData[name] = oldObject[name]
+			newData[name] = value
+		}
+	}
+	for name, value := range oldObject {
+		if _, exists := newObject[name]; !exists {
+			oldData[name] = value
+			newData[name] = nil
+		}
+	}
+	return oldData, newData
+}
+
+// jsonObject returns the members of a value that encodes to a JSON object
+func jsonObject(value interface{}) map[string]interface{} {
+	object := map[string]interface{}{}
+	data, _ := json.Marshal(value)
+	json.Unmarshal(data, &object)
+	return object
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+type patchableContact struct {
+	Phone string `json:"phone"`
+	Email string `json:"email"`
+}
+
+type patchableSite struct {
+	Name    string           `json:"name" binding:"required"`
+	Tinggi  *float64         `json:"tinggi"`
+	Contact patchableContact `json:"contact"`
+}
+
+func TestBindMergePatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tinggi := 30.0
+	current := patchableSite{Name: "Paruga", Tinggi: &tinggi, Contact: patchableContact{Phone: "0811", Email: "site@example.com"}}
+
+	router := gin.New()
+	router.PATCH("/sites/1", func(c *gin.Context) {
+		var patched patchableSite
+		oldData, newData, ok := bindMergePatch(c, current, &patched)
+		if ok {
+			c.JSON(http.StatusOK, gin.H{"patched": patched, "old": oldData, "new": newData})
+		}
+	})
+
+	tests := []struct {
+		name, contentType, body string
+		status                  int
+		patched                 string // The patched site, or the error message
+		changes                 string // The new values of the changed fields
+	}{
+		{"null resets a field", mergePatchContentType, `{"tinggi": null}`, http.StatusOK,
+			`{"name": "Paruga", "tinggi": null, "contact": {"phone": "0811", "email": "site@example.com"}}`, `{"tinggi": null}`},
+		{"nested objects are merged", mergePatchContentType, `{"contact": {"phone": "0812"}}`, http.StatusOK,
+			`{"name": "Paruga", "tinggi": 30, "contact": {"phone": "0812", "email": "site@example.com"}}`, `{"contact": {"phone": "0812", "email": "site@example.com"}}`},
+		{"plain JSON is accepted", "application/json", `{"name": "Paruga Selatan"}`, http.StatusOK,
+			`{"name": "Paruga Selatan", "tinggi": 30, "contact": {"phone": "0811", "email": "site@example.com"}}`, `{"name": "Paruga Selatan"}`},
+		{"unchanged values are not reported", mergePatchContentType, `{"name": "Paruga"}`, http.StatusOK,
+			`{"name": "Paruga", "tinggi": 30, "contact": {"phone": "0811", "email": "site@example.com"}}`, `{}`},
+		{"wrong content type", "text/plain", `{"name": "Paruga"}`, http.StatusUnsupportedMediaType,
+			"PATCH requests must have a application/merge-patch+json body", ""},
+		{"patch that is not an object", mergePatchContentType, `["name"]`, http.StatusBadRequest,
+			"Invalid merge patch: the body must be a JSON object", ""},
+		{"malformed JSON", mergePatchContentType, `{"name": `, http.StatusBadRequest,
+			"Invalid merge patch: unexpected EOF", ""},
+		{"unknown fields", mergePatchContentType, `{"name": "Paruga", "status": "active", "id": 2, "version": 9}`, http.StatusBadRequest,
+			"Cannot patch id, status, version, patchable fields are contact, name, tinggi", ""},
+		{"unknown nested fields", mergePatchContentType, `{"contact": {"fax": "0370"}}`, http.StatusBadRequest,
+			`Invalid merge patch: json: unknown field "fax"`, ""},
+		{"wrong types", mergePatchContentType, `{"tinggi": "high"}`, http.StatusBadRequest,
+			"Invalid merge patch: json: cannot unmarshal string into Go struct field patchableSite.tinggi of type float64", ""},
+		{"validation", mergePatchContentType, `{"name": null}`, http.StatusBadRequest,
+			"Key: 'patchableSite.Name' Error:Field validation for 'Name' failed on the 'required' tag", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sendJSON(router, http.MethodPatch, "/sites/1", tt.contentType, tt.body, "")
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status != http.StatusOK {
+				var body struct {
+					Message string `json:"message"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, tt.patched, body.Message)
+				return
+			}
+
+			var body struct {
+				Patched json.RawMessage `json:"patched"`
+				New     json.RawMessage `json:"new"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.JSONEq(t, tt.patched, string(body.Patched))
+			assert.JSONEq(t, tt.changes, string(body.New))
+		})
+	}
+
+	w := sendJSON(router, http.MethodPatch, "/sites/1", "text/plain", `{}`, "")
+	assert.Equal(t, mergePatchContentType, w.Header().Get("Accept-Patch"))
+}
+
+func TestChangedFields(t *testing.T) {
+	oldData, newData := changedFields(
+		gin.H{"name": "Paruga", "address": "Jl. Sudirman", "tinggi": 30, "removed": true},
+		gin.H{"name": "Paruga", "address": "Jl. Soekarno-Hatta", "tinggi": 42},
+	)
+	assert.Equal(t, gin.H{"address": "Jl. Sudirman", "tinggi": float64(30), "removed": true}, oldData)
+	assert.Equal(t, gin.H{"address": "Jl. Soekarno-Hatta", "tinggi": float64(42), "removed": nil}, newData)
+
+	oldData, newData = changedFields(ProviderInput{Name: "Operator A"}, ProviderInput{Name: "Operator A"})
+	assert.Empty(t, oldData)
+	assert.Empty(t, newData)
+}
+
+func TestPatchRejectsImmutableFields(t *testing.T) {
+	testutil.Setup(t, nil)
+	router := gin.New()
+	router.PATCH("/api/providers/:id", PatchProvider)
+	provider := models.Provider{Name: "Operator A"}
+	require.NoError(t, database.DB.Create(&provider).Error)
+	path := fmt.Sprintf("/api/providers/%d", provider.ID)
+
+	for _, body := range []string{`{"id": 99}`, `{"version": 9}`, `{"created_at": "2020-01-01T00:00:00Z"}`} {
+		w := sendJSON(router, http.MethodPatch, path, mergePatchContentType, body, helper.ETag(provider.Version))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), "patchable fields are address, name", body)
+	}
+
+	var current models.Provider
+	require.NoError(t, database.DB.First(&current, provider.ID).Error)
+	assert.Equal(t, provider.Version, current.Version)
+	assert.Equal(t, "Operator A", current.Name)
+}
@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/oidc"
+)
+
+// oidcFlowCookie carries the state, nonce and PKCE verifier of a sign-in from OIDCLogin to OIDCCallback
+const (
+	oidcFlowCookie = "oidc_flow"
+	oidcFlowPath   = "/api/auth/oidc"
+	oidcFlowTTL    = 10 * time.Minute
+)
+
+// errSSODenied is returned for identities that map to no role
+var errSSODenied = errors.New("identity is not a member of any group with access")
+
+// oidcFlowClaims is the signed content of the flow cookie
+type oidcFlowClaims struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// oidcRole is the account role an identity's groups map to
+type oidcRole struct {
+	ProviderID *uint // Nil for staff accounts
+	IsApprover bool
+}
+
+// OIDCLogin starts a single sign-on by redirecting to the login page of the identity provider
+func OIDCLogin(c *gin.Context) {
+	if oidc.Default == nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Single sign-on is not enabled")
+		return
+	}
+
+	var flow oidcFlowClaims
+	var err error
+	for _, value := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		if *value, err = oidc.RandomString(); err != nil {
+			helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to start single sign-on")
+			return
+		}
+	}
+	flow.ExpiresAt = jwt.NewNumericDate(time.Now().Add(oidcFlowTTL))
+	cookie, err := jwt.NewWithClaims(jwt.SigningMethodHS256, flow).SignedString(helper.JWTSecret())
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to start single sign-on")
+		return
+	}
+
+	authURL, err := oidc.Default.AuthCodeURL(c.Request.Context(), flow.State, flow.Nonce, flow.Verifier)
+	if err != nil {
+		helper.Logger(c).Error("Failed to reach identity provider", "error", err)
+		helper.SendErrorResponse(c, http.StatusBadGateway, "Identity provider is unavailable")
+		return
+	}
+
+	setOIDCFlowCookie(c, cookie, int(oidcFlowTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes a single sign-on. It redeems the authorization code, signs the user in,
+// creating or updating the account from the identity claims, and redirects to the frontend with
+// the app's own token in the URL fragment, or an error code if the sign-in failed.
+func OIDCCallback(c *gin.Context) {
+	if oidc.Default == nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Single sign-on is not enabled")
+		return
+	}
+
+	// The flow cookie is single use
+	cookie, _ := c.Cookie(oidcFlowCookie)
+	setOIDCFlowCookie(c, "", -1)
+
+	var flow oidcFlowClaims
+	_, err := jwt.ParseWithClaims(cookie, &flow, func(token *jwt.Token) (interface{}, error) {
+		return helper.JWTSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithExpirationRequired())
+	if err != nil || flow.State == "" || c.Query("state") != flow.State {
+		helper.Logger(c).Warn("Single sign-on callback with invalid state", "error", err)
+		redirectOIDCResult(c, url.Values{"error": {"invalid_state"}})
+		return
+	}
+	if idpError := c.Query("error"); idpError != "" {
+		helper.Logger(c).Info("Identity provider refused single sign-on", "error", idpError, "description", c.Query("error_description"))
+		redirectOIDCResult(c, url.Values{"error": {idpError}})
+		return
+	}
+
+	rawIDToken, err := oidc.Default.Exchange(c.Request.Context(), c.Query("code"), flow.Verifier)
+	if err != nil {
+		helper.Logger(c).Error("Failed to redeem authorization code", "error", err)
+		redirectOIDCResult(c, url.Values{"error": {"exchange_failed"}})
+		return
+	}
+	claims, err := oidc.Default.Verify(c.Request.Context(), rawIDToken, flow.Nonce)
+	if err != nil {
+		helper.Logger(c).Warn("Rejected ID token", "error", err)
+		redirectOIDCResult(c, url.Values{"error": {"invalid_token"}})
+		return
+	}
+
+	user, err := provisionOIDCUser(c, claims)
+	if err != nil {
+		if errors.Is(err, errSSODenied) {
+			recordLoginEvent(c, nil, claims.Email, false, models.LoginFailureSSODenied)
+			helper.Logger(c).Warn("Single sign-on denied", "subject", claims.Subject, "email", claims.Email, "groups", claims.Groups)
+			redirectOIDCResult(c, url.Values{"error": {"access_denied"}})
+			return
+		}
+		helper.Logger(c).Error("Failed to provision single sign-on account", "subject", claims.Subject, "error", err)
+		redirectOIDCResult(c, url.Values{"error": {"account_unavailable"}})
+		return
+	}
+
+	recordLoginEvent(c, &user.ID, user.Email, true, "")
+	checkLoginAnomalies(c, user)
+
+	token, err := helper.GenerateJWT(user.ID)
+	if err != nil {
+		redirectOIDCResult(c, url.Values{"error": {"server_error"}})
+		return
+	}
+	helper.Logger(c).Info("Single sign-on successful", "target_user_id", user.ID)
+	redirectOIDCResult(c, url.Values{"token": {token}})
+}
+
+// setOIDCFlowCookie sets or, with a negative maxAge, clears the flow cookie. It must survive the
+// top-level redirect back from the identity provider, hence SameSite=Lax.
+func setOIDCFlowCookie(c *gin.Context, value string, maxAge int) {
+	secure := strings.HasPrefix(config.Current.OIDC.RedirectURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcFlowCookie, value, maxAge, oidcFlowPath, "", secure, true)
+}
+
+// redirectOIDCResult sends the browser to the frontend with the result in the URL fragment, which
+// is not sent to servers and so keeps the token out of their logs
+func redirectOIDCResult(c *gin.Context, result url.Values) {
+	c.Redirect(http.StatusFound, config.Current.OIDC.LoginRedirectURL+"#"+result.Encode())
+}
+
+// resolveOIDCRole maps the groups of an identity to an account role. Staff groups take precedence
+// over provider groups; identities in neither are denied.
+func resolveOIDCRole(groups []string) (*oidcRole, error) {
+	cfg := config.Current.OIDC
+	role := &oidcRole{}
+	for _, group := range groups {
+		if slices.Contains(cfg.ApproverGroups, group) {
+			role.IsApprover = true
+		}
+	}
+	for _, group := range groups {
+		if slices.Contains(cfg.StaffGroups, group) {
+			return role, nil
+		}
+	}
+
+	for _, group := range groups {
+		providerName, ok := cfg.ProviderGroups[group]
+		if !ok {
+			continue
+		}
+		var provider models.Provider
+		if err := database.DB.Where("name = ?", providerName).First(&provider).Error; err != nil {
+			return nil, fmt.Errorf("provider %q of group %q: %w", providerName, group, err)
+		}
+		role.ProviderID = &provider.ID
+		return role, nil
+	}
+	return nil, errSSODenied
+}
+
+// provisionOIDCUser returns the account of a single sign-on identity. Accounts are found by
+// subject, then by verified email, which links an existing account and removes its local password.
+// Otherwise a new account is created. The role is updated from the groups every time.
+func provisionOIDCUser(c *gin.Context, claims *oidc.Claims) (*models.User, error) {
+	role, err := resolveOIDCRole(claims.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := database.DB.Where("oidc_subject = ?", claims.Subject).Limit(1).Find(&user).Error; err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		if claims.Email == "" {
+			return nil, errors.New("ID token has no email claim")
+		}
+		if err := database.DB.Where("email = ?", claims.Email).Limit(1).Find(&user).Error; err != nil {
+			return nil, err
+		}
+		if user.ID != 0 && !claims.EmailVerified {
+			return nil, fmt.Errorf("email %s of an existing account is not verified by the identity provider", claims.Email)
+		}
+	}
+
+	updates := map[string]interface{}{
+		"oidc_subject": claims.Subject,
+		"provider_id":  role.ProviderID,
+		"is_approver":  role.IsApprover,
+	}
+	switch {
+	case user.ID == 0:
+		name := claims.Name
+		if name == "" {
+			name = claims.Email
+		}
+		user = models.User{Name: name, Email: claims.Email, OIDCSubject: &claims.Subject, ProviderID: role.ProviderID, IsApprover: role.IsApprover}
+		if err := database.DB.Create(&user).Error; err != nil {
+			return nil, err
+		}
+		helper.Logger(c).Info("Single sign-on account created", "target_user_id", user.ID, "subject", claims.Subject)
+		return &user, nil
+	case user.OIDCSubject == nil:
+		updates["password"] = ""
+		helper.Logger(c).Info("Account linked to single sign-on identity", "target_user_id", user.ID, "subject", claims.Subject)
+	}
+
+	if err := database.DB.Model(&user).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/oidc"
+	"github.com/user/tower-tracker-bima/backend/oidc/oidctest"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+const loginRedirectURL = "http://localhost:5173/login/callback"
+
+// setupOIDC connects a test database and enables single sign-on against a mock identity provider
+func setupOIDC(t *testing.T) (*gin.Engine, *oidctest.Server) {
+	server := oidctest.NewServer(t, "tower-tracker")
+	testutil.Setup(t, map[string]string{
+		"OIDC_ISSUER_URL":         server.Issuer(),
+		"OIDC_CLIENT_ID":          "tower-tracker",
+		"OIDC_LOGIN_REDIRECT_URL": loginRedirectURL,
+		"OIDC_STAFF_GROUPS":       "staff",
+		"OIDC_APPROVER_GROUPS":    "approvers",
+		"OIDC_PROVIDER_GROUPS":    "operators-a=Operator A",
+	})
+	oidc.InitOIDC()
+	t.Cleanup(func() { oidc.Default = nil })
+	require.NoError(t, database.DB.Create(&models.Provider{Name: "Operator A"}).Error)
+
+	router := gin.New()
+	router.GET("/api/auth/oidc/login", OIDCLogin)
+	router.GET("/api/auth/oidc/callback", OIDCCallback)
+	return router, server
+}
+
+// oidcLogin starts a sign-in and returns the flow cookie and the URL of the login page
+func oidcLogin(t *testing.T, router *gin.Engine) (*http.Cookie, string) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, oidcFlowCookie, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+	return cookies[0], w.Header().Get("Location")
+}
+
+// oidcCallback returns to the callback and returns the parsed fragment of the frontend redirect
+func oidcCallback(t *testing.T, router *gin.Engine, cookie *http.Cookie, code, state string) url.Values {
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?"+url.Values{"code": {code}, "state": {state}}.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusFound, w.Code)
+
+	location := w.Header().Get("Location")
+	require.True(t, strings.HasPrefix(location, loginRedirectURL+"#"), location)
+	result, err := url.ParseQuery(strings.TrimPrefix(location, loginRedirectURL+"#"))
+	require.NoError(t, err)
+	return result
+}
+
+// signInWithSSO runs a complete sign-in for an identity with claims and returns the redirect fragment
+func signInWithSSO(t *testing.T, router *gin.Engine, server *oidctest.Server, claims jwt.MapClaims) url.Values {
+	cookie, authURL := oidcLogin(t, router)
+	code, state, err := server.Authorize(authURL, claims)
+	require.NoError(t, err)
+	return oidcCallback(t, router, cookie, code, state)
+}
+
+func TestOIDCCallbackMapsGroupsToRoles(t *testing.T) {
+	router, server := setupOIDC(t)
+	var provider models.Provider
+	require.NoError(t, database.DB.Where("name = ?", "Operator A").First(&provider).Error)
+
+	tests := []struct {
+		name       string
+		groups     []string
+		providerID *uint
+		isApprover bool
+	}{
+		{"staff", []string{"staff"}, nil, false},
+		{"approving staff", []string{"staff", "approvers"}, nil, true},
+		{"operator", []string{"operators-a"}, &provider.ID, false},
+		{"staff takes precedence", []string{"operators-a", "staff"}, nil, false},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject := "subject-" + string(rune('a'+i))
+			result := signInWithSSO(t, router, server, jwt.MapClaims{
+				"sub":            subject,
+				"email":          subject + "@example.com",
+				"email_verified": true,
+				"groups":         tt.groups,
+			})
+			require.NotEmpty(t, result.Get("token"), result.Get("error"))
+
+			claims, err := helper.ParseJWT(result.Get("token"))
+			require.NoError(t, err)
+			var user models.User
+			require.NoError(t, database.DB.Where("oidc_subject = ?", subject).First(&user).Error)
+			assert.Equal(t, user.ID, claims.UserID)
+			assert.Equal(t, tt.providerID, user.ProviderID)
+			assert.Equal(t, tt.isApprover, user.IsApprover)
+		})
+	}
+}
+
+func TestOIDCCallbackUpdatesRoleOnEverySignIn(t *testing.T) {
+	router, server := setupOIDC(t)
+	claims := jwt.MapClaims{"sub": "subject-1", "email": "jane@example.com", "groups": []string{"staff", "approvers"}}
+	require.NotEmpty(t, signInWithSSO(t, router, server, claims).Get("token"))
+
+	claims["groups"] = []string{"operators-a"}
+	require.NotEmpty(t, signInWithSSO(t, router, server, claims).Get("token"))
+
+	var user models.User
+	require.NoError(t, database.DB.Where("oidc_subject = ?", "subject-1").First(&user).Error)
+	assert.NotNil(t, user.ProviderID)
+	assert.False(t, user.IsApprover)
+}
+
+func TestOIDCCallbackDeniesUnmappedGroups(t *testing.T) {
+	router, server := setupOIDC(t)
+
+	result := signInWithSSO(t, router, server, jwt.MapClaims{"sub": "subject-1", "email": "jane@example.com", "groups": []string{"contractors"}})
+	assert.Equal(t, "access_denied", result.Get("error"))
+	assert.Empty(t, result.Get("token"))
+
+	var count int64
+	database.DB.Model(&models.User{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	router, server := setupOIDC(t)
+	cookie, authURL := oidcLogin(t, router)
+	code, _, err := server.Authorize(authURL, jwt.MapClaims{"sub": "subject-1", "groups": []string{"staff"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "invalid_state", oidcCallback(t, router, cookie, code, "forged-state").Get("error"))
+}
+
+func TestOIDCCallbackRejectsMissingFlowCookie(t *testing.T) {
+	router, server := setupOIDC(t)
+	_, authURL := oidcLogin(t, router)
+	code, state, err := server.Authorize(authURL, jwt.MapClaims{"sub": "subject-1", "groups": []string{"staff"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "invalid_state", oidcCallback(t, router, nil, code, state).Get("error"))
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	router, server := setupOIDC(t)
+
+	result := signInWithSSO(t, router, server, jwt.MapClaims{"sub": "subject-1", "nonce": "replayed-nonce", "groups": []string{"staff"}})
+	assert.Equal(t, "invalid_token", result.Get("error"))
+	assert.Empty(t, result.Get("token"))
+}
+
+func TestOIDCCallbackRejectsBadSignature(t *testing.T) {
+	router, server := setupOIDC(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	cookie, authURL := oidcLogin(t, router)
+	code, state, err := server.AuthorizeWithKey(authURL, jwt.MapClaims{"sub": "subject-1", "groups": []string{"staff"}}, otherKey)
+	require.NoError(t, err)
+
+	result := oidcCallback(t, router, cookie, code, state)
+	assert.Equal(t, "invalid_token", result.Get("error"))
+	assert.Empty(t, result.Get("token"))
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/mailer"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"gorm.io/gorm"
+)
+
+// errInvalidResetToken is returned for reset tokens that are unknown, expired or already used
+var errInvalidResetToken = errors.New("invalid or expired reset token")
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPassword emails a password reset link to the account with the given email. The response
+// is the same whether or not the account exists, so it cannot be used to probe for accounts.
+func ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	const message = "If the email address belongs to an account, a password reset link has been sent to it"
+
+	var user models.User
+	if err := database.DB.Where("email = ?", input.Email).Limit(1).Find(&user).Error; err != nil {
+		helper.Logger(c).Error("Failed to look up user for password reset", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to request password reset")
+		return
+	}
+	if user.ID == 0 {
+		helper.Logger(c).Info("Password reset requested for unknown email", "email", input.Email)
+		helper.SendSuccessResponse(c, http.StatusOK, message, nil)
+		return
+	}
+
+	if user.OIDCSubject != nil {
+		helper.Logger(c).Info("Password reset requested for single sign-on account", "target_user_id", user.ID)
+		helper.SendSuccessResponse(c, http.StatusOK, message, nil)
+		return
+	}
+
+	// The token is issued and mailed in the background, so that known addresses are not answered
+	// more slowly than unknown ones. Failures are only logged for the same reason.
+	logger := helper.Logger(c)
+	mailer.Go(func(ctx context.Context) {
+		token, err := issuePasswordResetToken(user.ID)
+		if err != nil {
+			logger.Error("Failed to issue password reset token", "target_user_id", user.ID, "error", err)
+			return
+		}
+		if err := mailer.Default.Send(ctx, passwordResetMail(user, token)); err != nil {
+			logger.Error("Failed to send password reset mail", "target_user_id", user.ID, "error", err)
+			return
+		}
+		logger.Info("Password reset mail sent", "target_user_id", user.ID)
+	})
+	helper.SendSuccessResponse(c, http.StatusOK, message, nil)
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ResetPassword sets a new password using a token emailed by ForgotPassword. The token can only be
+// used once; the account is unlocked as well.
+func ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var token models.PasswordResetToken
+	err := database.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashResetToken(input.Token), time.Now()).
+		Limit(1).Find(&token).Error
+	if err != nil {
+		helper.Logger(c).Error("Failed to look up password reset token", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+	var user models.User
+	if token.ID == 0 || database.DB.First(&user, token.UserID).Error != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid or expired reset token")
+		return
+	}
+
+	if err := helper.ValidatePassword(input.NewPassword, user.Email); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	hashedPassword, err := helper.HashPassword(input.NewPassword)
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to hash new password")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// The condition on used_at makes sure concurrent requests cannot both use the token
+		now := time.Now()
+		result := tx.Model(&models.PasswordResetToken{}).Where("id = ? AND used_at IS NULL", token.ID).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errInvalidResetToken
+		}
+
+		err := tx.Model(&models.User{}).Where("id = ?", token.UserID).Updates(map[string]interface{}{
+			"password":              hashedPassword,
+			"password_changed_at":   now, // Logs out sessions that may have been taken over
+			"failed_login_attempts": 0,
+			"locked_until":          nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return invalidatePasswordResetTokens(tx, token.UserID)
+	})
+	if errors.Is(err, errInvalidResetToken) {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		helper.Logger(c).Error("Failed to reset password", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	helper.Logger(c).Info("Password reset", "target_user_id", user.ID)
+	helper.SendSuccessResponse(c, http.StatusOK, "Password has been reset", nil)
+}
+
+// issuePasswordResetToken creates a reset token for the user, superseding any earlier ones, and
+// returns it. Only its hash is stored.
+func issuePasswordResetToken(userID uint) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := invalidatePasswordResetTokens(tx, userID); err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    userID,
+			TokenHash: hashResetToken(token),
+			ExpiresAt: time.Now().Add(config.Current.Auth.PasswordResetTTL),
+		}).Error
+	})
+	return token, err
+}
+
+// invalidatePasswordResetTokens marks every unused reset token of the user as used
+func invalidatePasswordResetTokens(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.PasswordResetToken{}).Where("user_id = ? AND used_at IS NULL", userID).Update("used_at", time.Now()).Error
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// passwordResetMail builds the email carrying a reset link for token
+func passwordResetMail(user models.User, token string) mailer.Message {
+	cfg := config.Current.Auth
+	link, _ := url.Parse(cfg.PasswordResetURL) // Validated by config.Load
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Tower Tracker password",
+		Body: fmt.Sprintf(`Hello %s,
+
+Someone requested a password reset for your Tower Tracker account. Open the link below to choose
+a new password. It is valid for %d minutes and can only be used once.
+
+%s
+
+If you did not request this, you can ignore this email; your password stays unchanged.
+`, user.Name, int(cfg.PasswordResetTTL.Minutes()), link),
+	}
+}
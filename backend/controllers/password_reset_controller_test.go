@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/mailer"
+	"github.com/user/tower-tracker-bima/backend/mailer/mailtest"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+// setupPasswordReset connects a test database with one account whose mail goes to an SMTP sink
+func setupPasswordReset(t *testing.T) (*gin.Engine, *mailtest.SMTPSink) {
+	sink := mailtest.NewSMTPSink(t)
+	env := sink.Env()
+	env["PASSWORD_RESET_URL"] = "http://app.test/reset-password"
+	testutil.Setup(t, env)
+	previous := mailer.Default
+	mailer.InitMailer()
+	t.Cleanup(func() { mailer.Default = previous })
+
+	hash, err := helper.HashPassword("Old-Secret42")
+	require.NoError(t, err)
+	require.NoError(t, database.DB.Create(&models.User{Name: "Jane", Email: "jane@example.com", Password: hash}).Error)
+
+	router := gin.New()
+	router.POST("/api/auth/forgot-password", ForgotPassword)
+	router.POST("/api/auth/reset-password", ResetPassword)
+	return router, sink
+}
+
+func postJSON(router *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// waitForMails waits until the mails sent in the background have been handed off
+func waitForMails(t *testing.T) {
+	require.NoError(t, mailer.Wait(context.Background()))
+}
+
+var resetTokenPattern = regexp.MustCompile(`http://app\.test/reset-password\?token=([0-9a-f]{64})`)
+
+func TestForgotPasswordMailsResetLink(t *testing.T) {
+	router, sink := setupPasswordReset(t)
+
+	w := postJSON(router, "/api/auth/forgot-password", `{"email": "jane@example.com"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	waitForMails(t)
+
+	mails := sink.Mails()
+	require.Len(t, mails, 1)
+	assert.Equal(t, []string{"jane@example.com"}, mails[0].To)
+	match := resetTokenPattern.FindStringSubmatch(mails[0].Data)
+	require.NotNil(t, match, mails[0].Data)
+
+	w = postJSON(router, "/api/auth/reset-password", `{"token": "`+match[1]+`", "new_password": "Xyzzy-Secret9"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var user models.User
+	require.NoError(t, database.DB.Where("email = ?", "jane@example.com").First(&user).Error)
+	assert.True(t, helper.CheckPasswordHash("Xyzzy-Secret9", user.Password))
+	require.NotNil(t, user.PasswordChangedAt, "tokens issued before the reset are refused")
+	assert.WithinDuration(t, time.Now(), *user.PasswordChangedAt, time.Minute)
+
+	w = postJSON(router, "/api/auth/reset-password", `{"token": "`+match[1]+`", "new_password": "Other-Secret7"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "reset tokens are single use")
+}
+
+func TestForgotPasswordAnswersUnknownEmailsAlike(t *testing.T) {
+	router, sink := setupPasswordReset(t)
+
+	known := postJSON(router, "/api/auth/forgot-password", `{"email": "jane@example.com"}`)
+	unknown := postJSON(router, "/api/auth/forgot-password", `{"email": "nobody@example.com"}`)
+	waitForMails(t)
+
+	assert.Equal(t, known.Code, unknown.Code)
+	assert.Equal(t, known.Body.String(), unknown.Body.String())
+	assert.Len(t, sink.Mails(), 1, "only the account gets a mail")
+}
+
+// blockingMailer holds every mail until released
+type blockingMailer struct {
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	<-m.release
+	return nil
+}
+
+func TestForgotPasswordDoesNotWaitForMail(t *testing.T) {
+	router, _ := setupPasswordReset(t)
+	blocking := &blockingMailer{release: make(chan struct{})}
+	mailer.Default = blocking
+
+	w := postJSON(router, "/api/auth/forgot-password", `{"email": "jane@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code, "the response is sent while the mail is still pending")
+
+	close(blocking.release)
+	waitForMails(t)
+}
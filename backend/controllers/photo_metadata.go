@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jdeng/goheif"
+	"github.com/jdeng/goheif/heif"
+	"github.com/jdeng/goheif/heif/bmff"
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+)
+
+// PhotoMetadata holds the EXIF information extracted from an uploaded photo.
+// Fields are nil when the photo does not carry the corresponding tag.
+type PhotoMetadata struct {
+	Latitude  *float64   `json:"latitude"`
+	Longitude *float64   `json:"longitude"`
+	TakenAt   *time.Time `json:"taken_at"`
+
+	// Orientation is the EXIF orientation (1-8) the image must be rotated by to display upright.
+	// For HEIC photos it is derived from the rotation and mirroring of the image container.
+	Orientation int `json:"-"`
+}
+
+// HasLocation reports whether the photo carries GPS coordinates
+func (m *PhotoMetadata) HasLocation() bool {
+	return m != nil && m.Latitude != nil && m.Longitude != nil
+}
+
+// extractPhotoMetadata parses the EXIF block of a JPEG or HEIC upload. Images without
+// EXIF data, such as PNGs, yield empty metadata rather than an error.
+func extractPhotoMetadata(data []byte, format string) *PhotoMetadata {
+	metadata := &PhotoMetadata{}
+
+	exifData := data
+	if format == "heic" {
+		if item, err := heif.Open(bytes.NewReader(data)).PrimaryItem(); err == nil {
+			metadata.Orientation = heifOrientation(item)
+		}
+		var err error
+		if exifData, err = goheif.ExtractExif(bytes.NewReader(data)); err != nil {
+			return metadata
+		}
+	}
+
+	x, err := exif.Decode(bytes.NewReader(exifData))
+	if err != nil {
+		return metadata
+	}
+
+	if lat, lon, err := x.LatLong(); err == nil {
+		metadata.Latitude = &lat
+		metadata.Longitude = &lon
+	}
+	if takenAt, err := x.DateTime(); err == nil {
+		metadata.TakenAt = &takenAt
+	}
+	if tag, err := x.Get(exif.Orientation); err == nil && format != "heic" {
+		if orientation, err := tag.Int(0); err == nil {
+			metadata.Orientation = orientation
+		}
+	}
+	return metadata
+}
+
+// heifOrientations maps the rotation of a HEIF image, in steps of 90 degrees counter-clockwise,
+// followed by its mirroring, to the EXIF orientation with the same effect
+var heifOrientations = [3][4]int{
+	{1, 8, 3, 6}, // Not mirrored
+	{4, 5, 2, 7}, // Mirrored top to bottom, about the vertical axis in the words of the bmff package
+	{2, 7, 4, 5}, // Mirrored left to right
+}
+
+// heifOrientation returns the EXIF orientation equivalent to the rotation ("irot") and mirroring
+// ("imir") properties of a HEIF image. Readers must apply those, which the decoder does not do,
+// rather than the EXIF orientation, which cameras only set to match them.
+func heifOrientation(item *heif.Item) int {
+	rotations, mirror := 0, 0
+	for _, property := range item.Properties {
+		switch property := property.(type) {
+		case *bmff.ImageRotation:
+			rotations = int(property.Angle)
+		case *bmff.ImageMirror:
+			mirror = 1 + int(property.Mirror)
+		}
+	}
+	return heifOrientations[mirror][rotations]
+}
+
+// applyPhotoLocation copies the photo's GPS position onto the tower photo and flags it when it
+// was taken further from the tower's stored position than the configured distance.
+func applyPhotoLocation(towerPhoto *models.TowerPhoto, tower *models.Tower, metadata *PhotoMetadata) {
+	if !metadata.HasLocation() {
+		return
+	}
+
+	towerPhoto.Latitude = metadata.Latitude
+	towerPhoto.Longitude = metadata.Longitude
+
+	distance := helper.DistanceMeters(tower.Latitude, tower.Longitude, *metadata.Latitude, *metadata.Longitude)
+	towerPhoto.DistanceFromTower = &distance
+	towerPhoto.FarFromTower = distance > config.Current.Upload.PhotoMaxDistanceMeters
+}
+
+// ExtractPhotoMetadata returns the EXIF GPS position and capture time of an uploaded photo
+// without storing it, so that clients can prefill a tower's coordinates.
+func ExtractPhotoMetadata(c *gin.Context) {
+	if !parseUploadForm(c) {
+		return
+	}
+
+	file, err := c.FormFile("photo")
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "Photo file is required")
+		return
+	}
+
+	data, format, err := readImageUpload(file, uploadLimits())
+	if err != nil {
+		helper.SendErrorResponse(c, uploadErrorStatus(err), err.Error())
+		return
+	}
+
+	helper.SendSuccessResponse(c, http.StatusOK, "Photo metadata extracted successfully", extractPhotoMetadata(data, format))
+}
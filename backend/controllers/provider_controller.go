@@ -1,7 +1,7 @@
 package controllers
 
 import (
-	"log"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -16,23 +16,19 @@ type ProviderInput struct {
 }
 
 func CreateProvider(c *gin.Context) {
-	log.Println("CreateProvider: Function entered")
 	var input ProviderInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		log.Printf("CreateProvider: ShouldBindJSON error: %v", err)
 		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	log.Printf("CreateProvider Input: %+v", input)
-
 	provider := models.Provider{Name: input.Name, Address: input.Address}
 	if err := database.DB.Create(&provider).Error; err != nil {
-		log.Printf("CreateProvider: GORM Create error: %v", err)
+		helper.Logger(c).Error("Failed to create provider", "error", err)
 		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to create provider: "+err.Error())
 		return
 	}
-	log.Printf("CreateProvider: Provider successfully created in DB: %+v", provider)
+	helper.Logger(c).Info("Provider created", "provider_id", provider.ID)
 
 	helper.SendSuccessResponse(c, http.StatusCreated, "Provider created successfully", provider)
 }
@@ -49,15 +45,20 @@ func GetProvider(c *gin.Context) {
 		helper.SendErrorResponse(c, http.StatusNotFound, "Provider not found")
 		return
 	}
+	helper.SetETag(c, provider.Version)
 	helper.SendSuccessResponse(c, http.StatusOK, "Provider fetched successfully", provider)
 }
 
+// UpdateProvider replaces the name and address of a provider. If-Match must carry the provider's ETag.
 func UpdateProvider(c *gin.Context) {
 	var provider models.Provider
 	if err := database.DB.First(&provider, c.Param("id")).Error; err != nil {
 		helper.SendErrorResponse(c, http.StatusNotFound, "Provider not found")
 		return
 	}
+	if !checkIfMatch(c, provider.Version, func() { sendVersionConflict(c, provider.Version, provider) }) {
+		return
+	}
 
 	var input ProviderInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -69,10 +70,53 @@ func UpdateProvider(c *gin.Context) {
 		"Name":    input.Name,
 		"Address": input.Address,
 	}
-	database.DB.Model(&provider).Updates(updateData)
+	if !applyProviderUpdates(c, &provider, updateData) {
+		return
+	}
+	helper.SetETag(c, provider.Version)
 	helper.SendSuccessResponse(c, http.StatusOK, "Provider updated successfully", provider)
 }
 
+// PatchProvider changes the fields of a provider named in a JSON Merge Patch (RFC 7396), leaving
+// the others as they are. If-Match must carry the provider's ETag.
+func PatchProvider(c *gin.Context) {
+	var provider models.Provider
+	if err := database.DB.First(&provider, c.Param("id")).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Provider not found")
+		return
+	}
+	if !checkIfMatch(c, provider.Version, func() { sendVersionConflict(c, provider.Version, provider) }) {
+		return
+	}
+
+	var patched ProviderInput
+	_, newData, ok := bindMergePatch(c, ProviderInput{Name: provider.Name, Address: provider.Address}, &patched)
+	if !ok {
+		return
+	}
+	if len(newData) > 0 && !applyProviderUpdates(c, &provider, newData) {
+		return
+	}
+	helper.SetETag(c, provider.Version)
+	helper.SendSuccessResponse(c, http.StatusOK, "Provider updated successfully", provider)
+}
+
+// applyProviderUpdates writes updates to the provider, responding 412 with its current state if it
+// was changed since it was loaded
+func applyProviderUpdates(c *gin.Context, provider *models.Provider, updates map[string]interface{}) bool {
+	if err := updateVersioned(provider, provider.Version, updates); err != nil {
+		if errors.Is(err, errVersionConflict) {
+			database.DB.First(provider, provider.ID)
+			sendVersionConflict(c, provider.Version, provider)
+			return false
+		}
+		helper.Logger(c).Error("Failed to update provider", "provider_id", provider.ID, "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to update provider: "+err.Error())
+		return false
+	}
+	return true
+}
+
 func DeleteProvider(c *gin.Context) {
 	var provider models.Provider
 	// Use Unscoped to find the record even if it's soft-deleted
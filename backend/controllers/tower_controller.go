@@ -1,16 +1,17 @@
 package controllers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	_ "image/jpeg"
 	_ "image/png"
-	"log"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"time"
 
@@ -20,44 +21,129 @@ import (
 	"github.com/user/tower-tracker-bima/backend/database"
 	"github.com/user/tower-tracker-bima/backend/helper"
 	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/storage"
+	"golang.org/x/image/draw"
+	"gorm.io/gorm"
 )
 
-// processAndSaveWebP handles decoding an uploaded image, converting it to WebP, and saving it.
-func processAndSaveWebP(file *multipart.FileHeader) (string, error) {
-	// 1. Open the uploaded file
-	src, err := file.Open()
+// Maximum edge lengths (in pixels) of the renditions generated for every uploaded photo
+const (
+	mediumMaxEdge    = 1024
+	thumbnailMaxEdge = 256
+)
+
+// savedPhoto holds the URLs of an uploaded photo and its resized renditions,
+// along with the EXIF metadata read from the original upload
+type savedPhoto struct {
+	URL          string
+	MediumURL    string
+	ThumbnailURL string
+	Metadata     *PhotoMetadata
+}
+
+// processAndSaveWebP handles validating and decoding an uploaded image, converting it to WebP, and
+// saving it together with medium and thumbnail renditions. Originals larger than the configured
+// maximum edge are scaled down.
+func processAndSaveWebP(ctx context.Context, file *multipart.FileHeader) (*savedPhoto, error) {
+	limits := uploadLimits()
+
+	// 1. Read the uploaded file, enforcing size, type and pixel limits before decoding
+	data, format, err := readImageUpload(file, limits)
 	if err != nil {
-		return "", fmt.Errorf("failed to open uploaded file: %w", err)
+		return nil, err
 	}
-	defer src.Close()
 
-	// 2. Decode the image
-	img, _, err := image.Decode(src)
+	// 2. Extract EXIF metadata, which is lost once the image is re-encoded
+	metadata := extractPhotoMetadata(data, format)
+
+	// 3. Decode the image and bring it within the size limit, then upright. Rotating only the
+	// scaled-down image keeps the cost of large uploads low. Only the decoded pixels are
+	// re-encoded below, so EXIF, XMP and other metadata are stripped from the stored WebP.
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return "", fmt.Errorf("failed to decode image: %w", err)
+		return nil, &uploadError{http.StatusBadRequest, "Failed to decode image: " + err.Error()}
+	}
+	img = resizeToFit(img, limits.MaxEdge)
+	if format == "jpeg" || format == "heic" {
+		img = applyOrientation(img, metadata.Orientation)
 	}
 
-	// 3. Generate a unique filename with .webp extension
-	newFileName := uuid.New().String() + ".webp"
-	dstPath := filepath.Join("uploads", newFileName)
+	// 4. Generate a unique base name shared by all renditions
+	baseName := uuid.New().String()
 
-	// 4. Create the destination file
-	dstFile, err := os.Create(dstPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create destination file: %w", err)
+	// 5. Encode the original and each rendition to WebP and save
+	saved := &savedPhoto{Metadata: metadata}
+	if saved.URL, err = saveWebP(ctx, img, baseName+".webp", 90); err != nil {
+		return nil, err
+	}
+	if saved.MediumURL, err = saveWebP(ctx, resizeToFit(img, mediumMaxEdge), baseName+"_medium.webp", 85); err != nil {
+		removePhotoFiles(ctx, saved.URL)
+		return nil, err
+	}
+	if saved.ThumbnailURL, err = saveWebP(ctx, resizeToFit(img, thumbnailMaxEdge), baseName+"_thumb.webp", 80); err != nil {
+		removePhotoFiles(ctx, saved.URL, saved.MediumURL)
+		return nil, err
 	}
-	defer dstFile.Close()
 
-	// 5. Encode the image to WebP and save
-	if err := webp.Encode(dstFile, img, &webp.Options{Quality: 90}); err != nil {
+	// 6. Return the URL paths
+	return saved, nil
+}
+
+// saveWebP encodes img as WebP, stores it under key in the upload storage and returns its URL.
+func saveWebP(ctx context.Context, img image.Image, key string, quality float32) (string, error) {
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, img, &webp.Options{Quality: quality}); err != nil {
 		return "", fmt.Errorf("failed to encode image to webp: %w", err)
 	}
 
-	// 6. Return the URL path
-	return fmt.Sprintf("/uploads/%s", newFileName), nil
+	if err := storage.Default.Put(ctx, key, &buf, int64(buf.Len()), "image/webp"); err != nil {
+		return "", fmt.Errorf("failed to store photo: %w", err)
+	}
+
+	return storage.Default.URL(key), nil
+}
+
+// resizeToFit scales img down so that its longest edge is at most maxEdge pixels.
+// Images that already fit are returned unchanged.
+func resizeToFit(img image.Image, maxEdge int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxEdge && height <= maxEdge {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxEdge/width)
+		width = maxEdge
+	} else {
+		width = max(1, width*maxEdge/height)
+		height = maxEdge
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// removePhotoFiles deletes the stored files behind the given upload URLs, ignoring empty ones
+// and URLs that are not managed by the upload storage.
+func removePhotoFiles(ctx context.Context, urls ...string) {
+	for _, url := range urls {
+		key, ok := storage.Default.KeyFromURL(url)
+		if !ok {
+			continue
+		}
+		if err := storage.Default.Delete(ctx, key); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete photo file", "key", key, "error", err)
+		}
+	}
 }
 
 func CreateTower(c *gin.Context) {
+	if !parseUploadForm(c) {
+		return
+	}
+
 	// Parse form data
 	latStr := c.PostForm("latitude")
 	lonStr := c.PostForm("longitude")
@@ -68,21 +154,23 @@ func CreateTower(c *gin.Context) {
 	tinggiStr := c.PostForm("tinggi")
 	providerIDs := c.PostFormArray("provider_ids")
 
-	log.Printf("CreateTower: Received form data: lat=%s, lon=%s, kelurahan=%s, kecamatan=%s, tipe=%s, tinggi=%s, providers=%v", latStr, lonStr, kelurahan, kecamatan, tipe, tinggiStr, providerIDs)
+	// When no coordinates are given, they are taken from the photo's EXIF GPS position
+	usePhotoLocation := latStr == "" && lonStr == ""
 
-	c.Request.ParseMultipartForm(10 << 20) // 10 MB
-	log.Printf("Full form data: %v", c.Request.Form)
-
-	latitude, err := strconv.ParseFloat(latStr, 64)
-	if err != nil {
-		helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid latitude format")
-		return
-	}
+	var latitude, longitude float64
+	var err error
+	if !usePhotoLocation {
+		latitude, err = strconv.ParseFloat(latStr, 64)
+		if err != nil {
+			helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid latitude format")
+			return
+		}
 
-	longitude, err := strconv.ParseFloat(lonStr, 64)
-	if err != nil {
-		helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid longitude format")
-		return
+		longitude, err = strconv.ParseFloat(lonStr, 64)
+		if err != nil {
+			helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid longitude format")
+			return
+		}
 	}
 
 	var tinggi float64
@@ -95,20 +183,32 @@ func CreateTower(c *gin.Context) {
 	}
 
 	// Handle file upload and convert to WebP
+	var photo *savedPhoto
 	var photoURL string
 	file, err := c.FormFile("photo")
 	if err == nil { // Photo is provided
-		log.Println("CreateTower: Photo file found, processing...")
-		photoURL, err = processAndSaveWebP(file)
+		photo, err = processAndSaveWebP(c.Request.Context(), file)
 		if err != nil {
-			helper.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+			helper.SendErrorResponse(c, uploadErrorStatus(err), err.Error())
 			return
 		}
+		photoURL = photo.URL
 	} else if err != http.ErrMissingFile {
 		helper.SendErrorResponse(c, http.StatusBadRequest, "Error processing photo upload")
 		return
 	}
 
+	if usePhotoLocation {
+		if photo == nil || !photo.Metadata.HasLocation() {
+			if photo != nil {
+				removePhotoFiles(c.Request.Context(), photo.URL, photo.MediumURL, photo.ThumbnailURL)
+			}
+			helper.SendErrorResponse(c, http.StatusBadRequest, "Latitude and longitude are required when the photo has no GPS location")
+			return
+		}
+		latitude, longitude = *photo.Metadata.Latitude, *photo.Metadata.Longitude
+	}
+
 	// Find providers
 	var providers []*models.Provider
 	if len(providerIDs) > 0 {
@@ -135,16 +235,27 @@ func CreateTower(c *gin.Context) {
 		Status:    "active", // Default status
 	}
 
-	log.Printf("CreateTower: Attempting to create tower object: %+v", tower)
-
 	dbResult := database.DB.Create(&tower)
 	if dbResult.Error != nil {
-		log.Printf("CreateTower: DB.Create FAILED: %v", dbResult.Error)
+		helper.Logger(c).Error("Failed to create tower", "error", dbResult.Error)
+		if photo != nil {
+			removePhotoFiles(c.Request.Context(), photo.URL, photo.MediumURL, photo.ThumbnailURL)
+		}
 		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to create tower: "+dbResult.Error.Error())
 		return
 	}
 
-	log.Printf("CreateTower: DB.Create SUCCEEDED. Rows affected: %d. New Tower ID: %d", dbResult.RowsAffected, tower.ID)
+	helper.Logger(c).Info("Tower created", "tower_id", tower.ID, "providers", len(providers), "photo", photo != nil)
+
+	// Record the uploaded photo as the tower's first, primary photo
+	if photo != nil {
+		towerPhoto, err := addTowerPhoto(database.DB, c.MustGet("user_id").(uint), &tower, photo, "", nil, true)
+		if err != nil {
+			helper.Logger(c).Error("Failed to record tower photo", "tower_id", tower.ID, "error", err)
+		} else {
+			tower.Photos = []models.TowerPhoto{*towerPhoto}
+		}
+	}
 
 	// Create TowerEvent for creation
 	if err := createTowerEvent(c, tower.ID, "Created", "Tower initially created.", nil, gin.H{
@@ -158,136 +269,306 @@ func CreateTower(c *gin.Context) {
 		"providers": providers,
 		"status":    tower.Status,
 	}); err != nil {
-		fmt.Printf("Failed to create tower event for creation: %v\n", err)
+		helper.Logger(c).Error("Failed to create tower event for creation", "tower_id", tower.ID, "error", err)
 	}
 
+	presentTower(c.Request.Context(), &tower)
 	helper.SendSuccessResponse(c, http.StatusCreated, "Tower created successfully", tower)
 }
 
+// GetTowers lists all towers. With the lat, lng and radius (meters) query parameters,
+// only towers within that distance of the point are returned.
 func GetTowers(c *gin.Context) {
+	query := database.DB.Preload("Providers")
+
+	if c.Query("radius") != "" {
+		lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+		lng, lngErr := strconv.ParseFloat(c.Query("lng"), 64)
+		radius, radiusErr := strconv.ParseFloat(c.Query("radius"), 64)
+		if latErr != nil || lngErr != nil || radiusErr != nil || radius <= 0 {
+			helper.SendErrorResponse(c, http.StatusBadRequest, "lat, lng and a positive radius are required for a radius search")
+			return
+		}
+
+		ids, err := database.TowerIDsWithinRadius(lat, lng, radius)
+		if err != nil {
+			helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to search towers")
+			return
+		}
+		query = query.Where("id IN ?", ids)
+	}
+
 	var towers []models.Tower
-	database.DB.Preload("Providers").Find(&towers)
+	query.Find(&towers)
+	for i := range towers {
+		presentTower(c.Request.Context(), &towers[i])
+	}
 	helper.SendSuccessResponse(c, http.StatusOK, "Towers fetched successfully", towers)
 }
 
 func GetTower(c *gin.Context) {
 	var tower models.Tower
-	if err := database.DB.Preload("Providers").First(&tower, c.Param("id")).Error; err != nil {
+	if err := database.DB.Preload("Providers").Preload("Photos", orderedPhotos).First(&tower, c.Param("id")).Error; err != nil {
 		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
 		return
 	}
+	presentTower(c.Request.Context(), &tower)
+	helper.SetETag(c, tower.Version)
 	helper.SendSuccessResponse(c, http.StatusOK, "Tower fetched successfully", tower)
 }
 
+// towerDetailsChange holds new details of a tower. Nil fields are left unchanged.
+type towerDetailsChange struct {
+	Kelurahan *string  `json:"kelurahan,omitempty"`
+	Kecamatan *string  `json:"kecamatan,omitempty"`
+	Address   *string  `json:"address,omitempty"`
+	Tipe      *string  `json:"tipe,omitempty"`
+	Tinggi    *float64 `json:"tinggi,omitempty"`
+}
+
+// towerDetails are the details of a tower a PATCH may change
+type towerDetails struct {
+	Kelurahan string  `json:"kelurahan"`
+	Kecamatan string  `json:"kecamatan"`
+	Address   string  `json:"address"`
+	Tipe      string  `json:"tipe"`
+	Tinggi    float64 `json:"tinggi"`
+}
+
+// UpdateTower changes the details of a tower and optionally adds a new primary photo. Edits of
+// operator accounts are submitted for approval instead. If-Match must carry the tower's ETag.
 func UpdateTower(c *gin.Context) {
+	if !parseUploadForm(c) {
+		return
+	}
+
 	var tower models.Tower
-	if err := database.DB.First(&tower, c.Param("id")).Error; err != nil {
+	if err := database.DB.Preload("Providers").First(&tower, c.Param("id")).Error; err != nil {
 		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
 		return
 	}
-
-	// Store old data for event logging
-	oldData := gin.H{
-		"kelurahan": tower.Kelurahan,
-		"kecamatan": tower.Kecamatan,
-		"tinggi":    tower.Tinggi,
-		"tipe":      tower.Tipe,
-		"photo_url": tower.PhotoURL,
+	if !authorizeTowerEdit(c, &tower) || !checkIfMatch(c, tower.Version, func() { sendTowerConflict(c, tower.ID) }) {
+		return
 	}
 
-	// Parse form data for details
-	kelurahan := c.PostForm("kelurahan")
-	kecamatan := c.PostForm("kecamatan")
-	address := c.PostForm("address")
-	tipe := c.PostForm("tipe")
-	tinggiStr := c.PostForm("tinggi")
-
-	var tinggi float64
-	if tinggiStr != "" {
-		var err error
-		tinggi, err = strconv.ParseFloat(tinggiStr, 64)
+	// Parse form data for details. Omitted fields are cleared, except tinggi.
+	kelurahan, kecamatan := c.PostForm("kelurahan"), c.PostForm("kecamatan")
+	address, tipe := c.PostForm("address"), c.PostForm("tipe")
+	change := towerDetailsChange{Kelurahan: &kelurahan, Kecamatan: &kecamatan, Address: &address, Tipe: &tipe}
+	if tinggiStr := c.PostForm("tinggi"); tinggiStr != "" { // Only update if provided
+		tinggi, err := strconv.ParseFloat(tinggiStr, 64)
 		if err != nil {
 			helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid tinggi format")
 			return
 		}
+		change.Tinggi = &tinggi
 	}
 
-	// Handle file upload (optional)
+	// Handle file upload (optional). The new photo becomes the primary one,
+	// previous photos are kept in the tower's photo history.
+	var photo *models.PendingPhoto
 	file, err := c.FormFile("photo")
-	if err == nil { // Photo provided, update it
-		if tower.PhotoURL != "" {
-			oldPhotoPath := filepath.Join(".", tower.PhotoURL)
-			if err := os.Remove(oldPhotoPath); err != nil {
-				fmt.Printf("Failed to delete old photo file %s: %v\n", oldPhotoPath, err)
-			}
-		}
-		newPhotoURL, err := processAndSaveWebP(file)
+	if err == nil { // Photo provided, add it
+		saved, err := processAndSaveWebP(c.Request.Context(), file)
 		if err != nil {
-			helper.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+			helper.SendErrorResponse(c, uploadErrorStatus(err), err.Error())
 			return
 		}
-		tower.PhotoURL = newPhotoURL
+		photo = newPendingPhoto(saved, "", nil, true)
 	} else if err != http.ErrMissingFile {
 		helper.SendErrorResponse(c, http.StatusBadRequest, "Error processing photo upload")
 		return
 	}
 
-	// Update tower detail fields
-	tower.Kelurahan = kelurahan
-	tower.Kecamatan = kecamatan
-	tower.Address = address
-	tower.Tipe = tipe
-	if tinggiStr != "" { // Only update if provided
-		tower.Tinggi = tinggi
+	if _, isOperator := operatorProviderID(c); isOperator {
+		submitChangeRequest(c, &tower, "DetailsUpdate", towerDetailsData(&tower), change, photo)
+		return
 	}
 
-	if err := database.DB.Save(&tower).Error; err != nil {
+	if err := updateTowerDetails(c, &tower, change, photo, c.MustGet("user_id").(uint)); err != nil {
+		if photo != nil {
+			removePhotoFiles(c.Request.Context(), photo.URL, photo.MediumURL, photo.ThumbnailURL)
+		}
+		if errors.Is(err, errVersionConflict) {
+			sendTowerConflict(c, tower.ID)
+			return
+		}
 		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to update tower: "+err.Error())
 		return
 	}
 
-	// Create TowerEvent for details update
-	newData := gin.H{
+	presentTower(c.Request.Context(), &tower)
+	helper.SetETag(c, tower.Version)
+	helper.SendSuccessResponse(c, http.StatusOK, "Tower updated successfully", tower)
+}
+
+// PatchTower changes the details of a tower named in a JSON Merge Patch (RFC 7396), leaving the
+// others as they are. Edits of operator accounts are submitted for approval instead. If-Match must
+// carry the tower's ETag.
+func PatchTower(c *gin.Context) {
+	var tower models.Tower
+	if err := database.DB.Preload("Providers").First(&tower, c.Param("id")).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
+		return
+	}
+	if !authorizeTowerEdit(c, &tower) || !checkIfMatch(c, tower.Version, func() { sendTowerConflict(c, tower.ID) }) {
+		return
+	}
+
+	current := towerDetails{Kelurahan: tower.Kelurahan, Kecamatan: tower.Kecamatan, Address: tower.Address, Tipe: tower.Tipe, Tinggi: tower.Tinggi}
+	var patched towerDetails
+	oldData, newData, ok := bindMergePatch(c, current, &patched)
+	if !ok {
+		return
+	}
+
+	if len(newData) > 0 {
+		// Only the changed fields are applied, or submitted for approval
+		var change towerDetailsChange
+		data, _ := json.Marshal(newData)
+		json.Unmarshal(data, &change)
+
+		if _, isOperator := operatorProviderID(c); isOperator {
+			submitChangeRequest(c, &tower, "DetailsUpdate", oldData, change, nil)
+			return
+		}
+		if err := updateTowerDetails(c, &tower, change, nil, c.MustGet("user_id").(uint)); err != nil {
+			if errors.Is(err, errVersionConflict) {
+				sendTowerConflict(c, tower.ID)
+				return
+			}
+			helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to update tower: "+err.Error())
+			return
+		}
+	}
+
+	presentTower(c.Request.Context(), &tower)
+	helper.SetETag(c, tower.Version)
+	helper.SendSuccessResponse(c, http.StatusOK, "Tower updated successfully", tower)
+}
+
+// towerDetailsData returns the details of a tower recorded in DetailsUpdate events
+func towerDetailsData(tower *models.Tower) gin.H {
+	return gin.H{
 		"kelurahan": tower.Kelurahan,
 		"kecamatan": tower.Kecamatan,
+		"address":   tower.Address,
 		"tinggi":    tower.Tinggi,
 		"tipe":      tower.Tipe,
 		"photo_url": tower.PhotoURL,
 	}
+}
 
-	if fmt.Sprintf("%v", oldData) != fmt.Sprintf("%v", newData) {
-		if err := createTowerEvent(c, tower.ID, "DetailsUpdate", "Tower details were updated.", oldData, newData); err != nil {
-			fmt.Printf("Failed to create tower event for details update: %v\n", err)
+// updateTowerDetails applies change and the optional new primary photo to the tower on behalf of
+// the given user, and records the event with the fields that changed. It fails with errVersionConflict if the tower was changed
+// since it was loaded.
+func updateTowerDetails(c *gin.Context, tower *models.Tower, change towerDetailsChange, photo *models.PendingPhoto, userID uint) error {
+	// Store old data for event logging
+	oldData := towerDetailsData(tower)
+
+	// Update tower detail fields
+	if change.Kelurahan != nil {
+		tower.Kelurahan = *change.Kelurahan
+	}
+	if change.Kecamatan != nil {
+		tower.Kecamatan = *change.Kecamatan
+	}
+	if change.Address != nil {
+		tower.Address = *change.Address
+	}
+	if change.Tipe != nil {
+		tower.Tipe = *change.Tipe
+	}
+	if change.Tinggi != nil {
+		tower.Tinggi = *change.Tinggi
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveTower(tx, tower); err != nil {
+			return err
+		}
+		if photo != nil {
+			if _, err := addTowerPhoto(tx, userID, tower, pendingPhotoSaved(photo), photo.Caption, photo.TakenAt, photo.IsPrimary); err != nil {
+				return fmt.Errorf("failed to save tower photo: %w", err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	helper.SendSuccessResponse(c, http.StatusOK, "Tower updated successfully", tower)
+	// Create TowerEvent for details update, holding only the changed fields
+	oldData, newData := changedFields(oldData, towerDetailsData(tower))
+	if len(newData) > 0 {
+		if err := recordTowerEvent(userID, tower.ID, "DetailsUpdate", "Tower details were updated.", oldData, newData); err != nil {
+			helper.Logger(c).Error("Failed to create tower event for details update", "tower_id", tower.ID, "error", err)
+		}
+	}
+	return nil
 }
 
+// DeleteTower deletes a tower and its photos. Deleting skips approval, so it is refused in
+// four-eyes mode, where towers are dismantled through a change request instead. If-Match must
+// carry the tower's ETag.
 func DeleteTower(c *gin.Context) {
+	if needsFourEyes(c) {
+		helper.SendErrorResponse(c, http.StatusForbidden, "Towers cannot be deleted while changes need approval, dismantle the tower instead")
+		return
+	}
+
 	var tower models.Tower
 	if err := database.DB.First(&tower, c.Param("id")).Error; err != nil {
 		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
 		return
 	}
+	if !checkIfMatch(c, tower.Version, func() { sendTowerConflict(c, tower.ID) }) {
+		return
+	}
 
 	// Store old data for event logging
 	oldStatus := tower.Status
 
-	// Delete the associated photo file
-	if tower.PhotoURL != "" {
-		photoPath := filepath.Join(".", tower.PhotoURL)
-		if err := os.Remove(photoPath); err != nil {
-			fmt.Printf("Failed to delete photo file %s: %v\n", photoPath, err)
+	var photos []models.TowerPhoto
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("version = ?", tower.Version).Delete(&tower)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return errVersionConflict
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := tx.Where("tower_id = ?", tower.ID).Find(&photos).Error; err != nil {
+			return fmt.Errorf("failed to load tower photos: %w", err)
+		}
+		if err := tx.Unscoped().Where("tower_id = ?", tower.ID).Delete(&models.TowerPhoto{}).Error; err != nil {
+			return fmt.Errorf("failed to delete tower photos: %w", err)
 		}
+		return nil
+	})
+	if errors.Is(err, errVersionConflict) {
+		sendTowerConflict(c, tower.ID)
+		return
+	}
+	if err != nil {
+		helper.Logger(c).Error("Failed to delete tower", "tower_id", tower.ID, "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to delete tower")
+		return
 	}
 
-	database.DB.Delete(&tower)
+	// The files go once the records are gone, so a failed delete never leaves photos without files
+	for _, photo := range photos {
+		removePhotoFiles(c.Request.Context(), photo.URL, photo.MediumURL, photo.ThumbnailURL)
+	}
+	if len(photos) == 0 {
+		removePhotoFiles(c.Request.Context(), tower.PhotoURL)
+	}
 
 	// Create TowerEvent for dismantling
 	if err := createTowerEvent(c, tower.ID, "Dismantled", fmt.Sprintf("Tower deleted (status changed from %s to dismantled)", oldStatus), gin.H{"status": oldStatus}, gin.H{"status": "dismantled"}); err != nil {
-		fmt.Printf("Failed to create tower event for dismantling: %v\n", err)
+		helper.Logger(c).Error("Failed to create tower event for dismantling", "tower_id", tower.ID, "error", err)
 	}
+	helper.Logger(c).Info("Tower deleted", "tower_id", tower.ID, "photos", len(photos))
 
 	helper.SendSuccessResponse(c, http.StatusOK, "Tower deleted successfully", nil)
 }
@@ -320,8 +601,11 @@ func getProviderNames(providers []*models.Provider) []string {
 
 // Helper to create TowerEvent
 func createTowerEvent(c *gin.Context, towerID uint, eventType, description string, oldData, newData interface{}) error {
-	userID := c.MustGet("user_id").(uint)
+	return recordTowerEvent(c.MustGet("user_id").(uint), towerID, eventType, description, oldData, newData)
+}
 
+// recordTowerEvent creates a TowerEvent for an action of the given user
+func recordTowerEvent(userID, towerID uint, eventType, description string, oldData, newData interface{}) error {
 	oldDataJSON, _ := json.Marshal(oldData)
 	newDataJSON, _ := json.Marshal(newData)
 
@@ -361,6 +645,9 @@ func ChangeOwnership(c *gin.Context) {
 		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
 		return
 	}
+	if !authorizeTowerEdit(c, &tower) || !checkIfMatch(c, tower.Version, func() { sendTowerConflict(c, tower.ID) }) {
+		return
+	}
 
 	var newProvider models.Provider
 	if err := database.DB.First(&newProvider, input.NewProviderID).Error; err != nil {
@@ -368,28 +655,52 @@ func ChangeOwnership(c *gin.Context) {
 		return
 	}
 
-	// Store old provider info for event
-	oldProviders := tower.Providers
-	oldProviderNames := []string{}
-	for _, p := range oldProviders {
-		oldProviderNames = append(oldProviderNames, p.Name)
+	if _, isOperator := operatorProviderID(c); isOperator {
+		submitChangeRequest(c, &tower, "OwnershipChange", gin.H{"providers": getProviderNames(tower.Providers)},
+			gin.H{"new_provider_id": newProvider.ID, "providers": []string{newProvider.Name}}, nil)
+		return
 	}
 
-	// Update providers association
-	if err := database.DB.Model(&tower).Association("Providers").Replace(&newProvider); err != nil {
+	if err := changeTowerOwnership(c, &tower, &newProvider, c.MustGet("user_id").(uint)); err != nil {
+		if errors.Is(err, errVersionConflict) {
+			sendTowerConflict(c, tower.ID)
+			return
+		}
 		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to change tower ownership")
 		return
 	}
 
+	presentTower(c.Request.Context(), &tower)
+	helper.SetETag(c, tower.Version)
+	helper.SendSuccessResponse(c, http.StatusOK, "Tower ownership changed successfully", tower)
+}
+
+// changeTowerOwnership makes newProvider the only provider of the tower on behalf of the given user
+// and records the event. The tower's providers must be loaded. It fails with errVersionConflict if
+// the tower was changed since it was loaded.
+func changeTowerOwnership(c *gin.Context, tower *models.Tower, newProvider *models.Provider, userID uint) error {
+	// Store old provider info for event
+	oldProviderNames := getProviderNames(tower.Providers)
+
+	// Update providers association
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveTower(tx, tower); err != nil {
+			return err
+		}
+		return tx.Model(tower).Association("Providers").Replace(newProvider)
+	})
+	if err != nil {
+		return err
+	}
+
 	// Create TowerEvent
 	description := fmt.Sprintf("Ownership changed from %s to %s", oldProviderNames, newProvider.Name)
 	oldData := gin.H{"providers": oldProviderNames}
 	newData := gin.H{"providers": []string{newProvider.Name}}
-	if err := createTowerEvent(c, tower.ID, "OwnershipChange", description, oldData, newData); err != nil {
-		fmt.Printf("Failed to create tower event: %v\n", err)
+	if err := recordTowerEvent(userID, tower.ID, "OwnershipChange", description, oldData, newData); err != nil {
+		helper.Logger(c).Error("Failed to create tower event", "tower_id", tower.ID, "error", err)
 	}
-
-	helper.SendSuccessResponse(c, http.StatusOK, "Tower ownership changed successfully", tower)
+	return nil
 }
 
 // RelocateTowerInput defines input for relocating a tower
@@ -398,7 +709,8 @@ type RelocateTowerInput struct {
 	Longitude float64 `json:"longitude" binding:"required"`
 }
 
-// RelocateTower handles changing the location of a tower
+// RelocateTower handles changing the location of a tower. Relocations by operator accounts, and
+// in four-eyes mode by anyone, are submitted for approval instead. If-Match must carry the tower's ETag.
 func RelocateTower(c *gin.Context) {
 	towerID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -413,11 +725,36 @@ func RelocateTower(c *gin.Context) {
 	}
 
 	var tower models.Tower
-	if err := database.DB.First(&tower, towerID).Error; err != nil {
+	if err := database.DB.Preload("Providers").First(&tower, towerID).Error; err != nil {
 		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
 		return
 	}
+	if !authorizeTowerEdit(c, &tower) || !checkIfMatch(c, tower.Version, func() { sendTowerConflict(c, tower.ID) }) {
+		return
+	}
+
+	if needsFourEyes(c) {
+		submitChangeRequest(c, &tower, "Relocation", gin.H{"latitude": tower.Latitude, "longitude": tower.Longitude}, input, nil)
+		return
+	}
+
+	if err := relocateTower(c, &tower, input, c.MustGet("user_id").(uint)); err != nil {
+		if errors.Is(err, errVersionConflict) {
+			sendTowerConflict(c, tower.ID)
+			return
+		}
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to relocate tower")
+		return
+	}
+
+	presentTower(c.Request.Context(), &tower)
+	helper.SetETag(c, tower.Version)
+	helper.SendSuccessResponse(c, http.StatusOK, "Tower relocated successfully", tower)
+}
 
+// relocateTower moves the tower on behalf of the given user and records the event. It fails with
+// errVersionConflict if the tower was changed since it was loaded.
+func relocateTower(c *gin.Context, tower *models.Tower, input RelocateTowerInput, userID uint) error {
 	// Store old location for event
 	oldLatitude := tower.Latitude
 	oldLongitude := tower.Longitude
@@ -426,23 +763,22 @@ func RelocateTower(c *gin.Context) {
 	tower.Latitude = input.Latitude
 	tower.Longitude = input.Longitude
 
-	if err := database.DB.Save(&tower).Error; err != nil {
-		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to relocate tower")
-		return
+	if err := saveTower(database.DB, tower); err != nil {
+		return err
 	}
 
 	// Create TowerEvent
 	description := fmt.Sprintf("Tower relocated from (%.6f, %.6f) to (%.6f, %.6f)", oldLatitude, oldLongitude, tower.Latitude, tower.Longitude)
 	oldData := gin.H{"latitude": oldLatitude, "longitude": oldLongitude}
 	newData := gin.H{"latitude": tower.Latitude, "longitude": tower.Longitude}
-	if err := createTowerEvent(c, tower.ID, "Relocation", description, oldData, newData); err != nil {
-		fmt.Printf("Failed to create tower event: %v\n", err)
+	if err := recordTowerEvent(userID, tower.ID, "Relocation", description, oldData, newData); err != nil {
+		helper.Logger(c).Error("Failed to create tower event", "tower_id", tower.ID, "error", err)
 	}
-
-	helper.SendSuccessResponse(c, http.StatusOK, "Tower relocated successfully", tower)
+	return nil
 }
 
-// DismantleTower handles marking a tower as dismantled
+// DismantleTower handles marking a tower as dismantled. In four-eyes mode the dismantlement is
+// submitted for approval instead. If-Match must carry the tower's ETag.
 func DismantleTower(c *gin.Context) {
 	towerID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -455,27 +791,50 @@ func DismantleTower(c *gin.Context) {
 		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
 		return
 	}
+	if !checkIfMatch(c, tower.Version, func() { sendTowerConflict(c, tower.ID) }) {
+		return
+	}
+
+	if needsFourEyes(c) {
+		submitChangeRequest(c, &tower, "Dismantled", gin.H{"status": tower.Status}, gin.H{"status": "dismantled"}, nil)
+		return
+	}
 
+	if err := dismantleTower(c, &tower, c.MustGet("user_id").(uint)); err != nil {
+		if errors.Is(err, errVersionConflict) {
+			sendTowerConflict(c, tower.ID)
+			return
+		}
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to dismantle tower")
+		return
+	}
+
+	presentTower(c.Request.Context(), &tower)
+	helper.SetETag(c, tower.Version)
+	helper.SendSuccessResponse(c, http.StatusOK, "Tower dismantled successfully", tower)
+}
+
+// dismantleTower marks the tower as dismantled on behalf of the given user and records the event.
+// It fails with errVersionConflict if the tower was changed since it was loaded.
+func dismantleTower(c *gin.Context, tower *models.Tower, userID uint) error {
 	// Store old status for event
 	oldStatus := tower.Status
 
 	// Update tower status
 	tower.Status = "dismantled"
 
-	if err := database.DB.Save(&tower).Error; err != nil {
-		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to dismantle tower")
-		return
+	if err := saveTower(database.DB, tower); err != nil {
+		return err
 	}
 
 	// Create TowerEvent
 	description := fmt.Sprintf("Tower status changed from %s to %s", oldStatus, tower.Status)
 	oldData := gin.H{"status": oldStatus}
 	newData := gin.H{"status": tower.Status}
-	if err := createTowerEvent(c, tower.ID, "Dismantled", description, oldData, newData); err != nil {
-		fmt.Printf("Failed to create tower event: %v\n", err)
+	if err := recordTowerEvent(userID, tower.ID, "Dismantled", description, oldData, newData); err != nil {
+		helper.Logger(c).Error("Failed to create tower event", "tower_id", tower.ID, "error", err)
 	}
-
-	helper.SendSuccessResponse(c, http.StatusOK, "Tower dismantled successfully", tower)
+	return nil
 }
 
 // GetTowerHistory retrieves the event history for a specific tower
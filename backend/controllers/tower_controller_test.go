@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/storage"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+	"gorm.io/gorm"
+)
+
+// useMemoryStorage stores uploads in memory for the duration of the test
+func useMemoryStorage(t *testing.T) *storage.MemoryStorage {
+	previous := storage.Default
+	t.Cleanup(func() { storage.Default = previous })
+	store := storage.NewMemoryStorage("/uploads")
+	storage.Default = store
+	return store
+}
+
+// createTowerWithPhoto adds a tower with one photo whose files are stored in store
+func createTowerWithPhoto(t *testing.T, store *storage.MemoryStorage) (models.Tower, models.TowerPhoto) {
+	ctx := context.Background()
+	for _, key := range []string{"a.webp", "a_medium.webp", "a_thumb.webp"} {
+		require.NoError(t, store.Put(ctx, key, strings.NewReader("photo"), 5, "image/webp"))
+	}
+	tower := models.Tower{Kelurahan: "Paruga", Status: "active", PhotoURL: store.URL("a.webp")}
+	require.NoError(t, database.DB.Create(&tower).Error)
+	photo := models.TowerPhoto{TowerID: tower.ID, URL: store.URL("a.webp"), MediumURL: store.URL("a_medium.webp"), ThumbnailURL: store.URL("a_thumb.webp"), IsPrimary: true}
+	require.NoError(t, database.DB.Create(&photo).Error)
+	return tower, photo
+}
+
+// towerRequest sends a request to handler, mounted at pattern, as the user with the given ID
+func towerRequest(userID uint, method, pattern string, handler gin.HandlerFunc, path string, header http.Header) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Handle(method, pattern, signedInAs(userID), handler)
+	req := httptest.NewRequest(method, path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDeleteTower(t *testing.T) {
+	testutil.Setup(t, nil)
+	store := useMemoryStorage(t)
+	staff := createUser(t, "staff@example.com", false)
+	tower, _ := createTowerWithPhoto(t, store)
+
+	path := fmt.Sprintf("/api/towers/%d", tower.ID)
+	w := towerRequest(staff.ID, http.MethodDelete, "/api/towers/:id", DeleteTower, path, nil)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code, "without If-Match")
+	w = towerRequest(staff.ID, http.MethodDelete, "/api/towers/:id", DeleteTower, path, http.Header{"If-Match": {helper.ETag(tower.Version + 1)}})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code, "stale If-Match")
+	require.NoError(t, database.DB.First(&models.Tower{}, tower.ID).Error)
+
+	w = towerRequest(staff.ID, http.MethodDelete, "/api/towers/:id", DeleteTower, path, http.Header{"If-Match": {helper.ETag(tower.Version)}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.ErrorIs(t, database.DB.First(&models.Tower{}, tower.ID).Error, gorm.ErrRecordNotFound)
+	var photos int64
+	database.DB.Unscoped().Model(&models.TowerPhoto{}).Where("tower_id = ?", tower.ID).Count(&photos)
+	assert.Zero(t, photos)
+	objects, err := store.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+
+	var event models.TowerEvent
+	require.NoError(t, database.DB.Where("tower_id = ?", tower.ID).First(&event).Error)
+	assert.Equal(t, "Dismantled", event.EventType)
+}
+
+func TestDeleteTowerRefusedInFourEyesMode(t *testing.T) {
+	testutil.Setup(t, map[string]string{"FOUR_EYES_APPROVAL": "true"})
+	store := useMemoryStorage(t)
+	staff := createUser(t, "staff@example.com", true)
+	tower, _ := createTowerWithPhoto(t, store)
+
+	w := towerRequest(staff.ID, http.MethodDelete, "/api/towers/:id", DeleteTower, fmt.Sprintf("/api/towers/%d", tower.ID), http.Header{"If-Match": {helper.ETag(tower.Version)}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	require.NoError(t, database.DB.First(&models.Tower{}, tower.ID).Error)
+	objects, err := store.List(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, objects, 3)
+}
+
+func TestUploadTowerPhotoRequiresIfMatch(t *testing.T) {
+	testutil.Setup(t, nil)
+	store := useMemoryStorage(t)
+	staff := createUser(t, "staff@example.com", false)
+	tower, _ := createTowerWithPhoto(t, store)
+
+	upload := func(ifMatch string) int {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("photo", "photo.jpg")
+		require.NoError(t, err)
+		part.Write([]byte("not checked before If-Match"))
+		require.NoError(t, form.Close())
+
+		router := gin.New()
+		router.POST("/api/towers/:id/photos", signedInAs(staff.ID), UploadTowerPhoto)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/towers/%d/photos", tower.ID), &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusPreconditionRequired, upload(""))
+	assert.Equal(t, http.StatusPreconditionFailed, upload(helper.ETag(tower.Version+1)))
+	objects, err := store.List(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, objects, 3, "nothing is stored for refused uploads")
+}
@@ -0,0 +1,415 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"gorm.io/gorm"
+)
+
+// orderedPhotos is a preload scope returning tower photos in display order
+func orderedPhotos(db *gorm.DB) *gorm.DB {
+	return db.Order("sort_order asc, id asc")
+}
+
+// parseTakenAt accepts either an RFC 3339 timestamp or a plain date (YYYY-MM-DD).
+// An empty string yields a nil time.
+func parseTakenAt(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid taken_at format, expected RFC 3339 or YYYY-MM-DD")
+}
+
+// addTowerPhoto records an already saved photo for the tower, uploaded by the given user. The photo is
+// appended after the existing ones; it becomes the primary photo when requested or when it is the
+// tower's first photo. The capture time and GPS position default to the photo's EXIF metadata.
+// The tower's photo URL and version are updated to match its row.
+func addTowerPhoto(db *gorm.DB, uploadedBy uint, tower *models.Tower, photo *savedPhoto, caption string, takenAt *time.Time, primary bool) (*models.TowerPhoto, error) {
+	towerPhoto := models.TowerPhoto{
+		TowerID:      tower.ID,
+		URL:          photo.URL,
+		MediumURL:    photo.MediumURL,
+		ThumbnailURL: photo.ThumbnailURL,
+		Caption:      caption,
+		TakenAt:      takenAt,
+		UploadedBy:   uploadedBy,
+	}
+	if towerPhoto.TakenAt == nil && photo.Metadata != nil {
+		towerPhoto.TakenAt = photo.Metadata.TakenAt
+	}
+	applyPhotoLocation(&towerPhoto, tower, photo.Metadata)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := touchTower(tx, tower.ID, tower.Version); err != nil {
+			return err
+		}
+		var stats struct {
+			Count    int64
+			MaxOrder int
+		}
+		if err := tx.Model(&models.TowerPhoto{}).
+			Select("COUNT(*) AS count, COALESCE(MAX(sort_order), -1) AS max_order").
+			Where("tower_id = ?", tower.ID).
+			Scan(&stats).Error; err != nil {
+			return err
+		}
+
+		towerPhoto.SortOrder = stats.MaxOrder + 1
+		towerPhoto.IsPrimary = primary || stats.Count == 0
+
+		if towerPhoto.IsPrimary {
+			if err := tx.Model(&models.TowerPhoto{}).Where("tower_id = ?", tower.ID).Update("is_primary", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&towerPhoto).Error; err != nil {
+			return err
+		}
+		if err := syncPrimaryPhoto(tx, tower.ID); err != nil {
+			return err
+		}
+		var current models.Tower
+		if err := tx.Select("photo_url", "version").First(&current, tower.ID).Error; err != nil {
+			return err
+		}
+		tower.PhotoURL, tower.Version = current.PhotoURL, current.Version
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &towerPhoto, nil
+}
+
+// syncPrimaryPhoto makes sure a tower with photos has a primary one and mirrors its URL into
+// Tower.PhotoURL. Callers increment the tower's version with touchTower.
+func syncPrimaryPhoto(tx *gorm.DB, towerID uint) error {
+	var photos []models.TowerPhoto
+	if err := tx.Scopes(orderedPhotos).Where("tower_id = ?", towerID).Find(&photos).Error; err != nil {
+		return err
+	}
+
+	primaryURL := ""
+	for _, photo := range photos {
+		if photo.IsPrimary {
+			primaryURL = photo.URL
+			break
+		}
+	}
+	if primaryURL == "" && len(photos) > 0 {
+		if err := tx.Model(&photos[0]).Update("is_primary", true).Error; err != nil {
+			return err
+		}
+		primaryURL = photos[0].URL
+	}
+
+	return tx.Model(&models.Tower{}).Where("id = ?", towerID).UpdateColumn("photo_url", primaryURL).Error
+}
+
+// findTowerPhoto loads the photo identified by the :photo_id parameter, scoped to the tower in :id
+func findTowerPhoto(c *gin.Context) (*models.TowerPhoto, bool) {
+	var photo models.TowerPhoto
+	if err := database.DB.Where("tower_id = ?", c.Param("id")).First(&photo, c.Param("photo_id")).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Tower photo not found")
+		return nil, false
+	}
+	return &photo, true
+}
+
+// GetTowerPhotos lists the photos of a tower in display order
+func GetTowerPhotos(c *gin.Context) {
+	towerID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid tower ID")
+		return
+	}
+
+	var photos []models.TowerPhoto
+	if err := database.DB.Scopes(orderedPhotos).Where("tower_id = ?", towerID).Find(&photos).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch tower photos")
+		return
+	}
+
+	for i := range photos {
+		presentTowerPhoto(c.Request.Context(), &photos[i])
+	}
+	helper.SendSuccessResponse(c, http.StatusOK, "Tower photos fetched successfully", photos)
+}
+
+// UploadTowerPhoto adds a new photo to a tower. Photos uploaded by operator accounts are only
+// added once approved. If-Match must carry the tower's ETag.
+func UploadTowerPhoto(c *gin.Context) {
+	if !parseUploadForm(c) {
+		return
+	}
+
+	var tower models.Tower
+	if err := database.DB.Preload("Providers").First(&tower, c.Param("id")).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "Tower not found")
+		return
+	}
+	if !authorizeTowerEdit(c, &tower) || !checkIfMatch(c, tower.Version, func() { sendTowerConflict(c, tower.ID) }) {
+		return
+	}
+
+	takenAt, err := parseTakenAt(c.PostForm("taken_at"))
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	isPrimary, _ := strconv.ParseBool(c.PostForm("is_primary"))
+
+	file, err := c.FormFile("photo")
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "Photo file is required")
+		return
+	}
+
+	saved, err := processAndSaveWebP(c.Request.Context(), file)
+	if err != nil {
+		helper.SendErrorResponse(c, uploadErrorStatus(err), err.Error())
+		return
+	}
+	pending := newPendingPhoto(saved, c.PostForm("caption"), takenAt, isPrimary)
+
+	if _, isOperator := operatorProviderID(c); isOperator {
+		submitChangeRequest(c, &tower, "PhotoUpdate", nil, gin.H{
+			"photo_url":  pending.URL,
+			"caption":    pending.Caption,
+			"is_primary": pending.IsPrimary,
+		}, pending)
+		return
+	}
+
+	photo, err := uploadTowerPhoto(c, &tower, pending, c.MustGet("user_id").(uint))
+	if err != nil {
+		removePhotoFiles(c.Request.Context(), saved.URL, saved.MediumURL, saved.ThumbnailURL)
+		if errors.Is(err, errVersionConflict) {
+			sendTowerConflict(c, tower.ID)
+			return
+		}
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to save tower photo: "+err.Error())
+		return
+	}
+
+	presentTowerPhoto(c.Request.Context(), photo)
+	helper.SetETag(c, tower.Version)
+	helper.SendSuccessResponse(c, http.StatusCreated, "Tower photo uploaded successfully", photo)
+}
+
+// uploadTowerPhoto adds a stored photo to the tower on behalf of the given user and records the event
+func uploadTowerPhoto(c *gin.Context, tower *models.Tower, pending *models.PendingPhoto, userID uint) (*models.TowerPhoto, error) {
+	photo, err := addTowerPhoto(database.DB, userID, tower, pendingPhotoSaved(pending), pending.Caption, pending.TakenAt, pending.IsPrimary)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordTowerEvent(userID, tower.ID, "PhotoUpdate", "A new photo was uploaded for the tower.", nil, gin.H{
+		"photo_id":       photo.ID,
+		"photo_url":      photo.URL,
+		"caption":        photo.Caption,
+		"is_primary":     photo.IsPrimary,
+		"far_from_tower": photo.FarFromTower,
+	}); err != nil {
+		helper.Logger(c).Error("Failed to create tower event for photo upload", "tower_id", tower.ID, "error", err)
+	}
+	return photo, nil
+}
+
+// UpdateTowerPhotoInput defines the editable metadata of a tower photo
+type UpdateTowerPhotoInput struct {
+	Caption   *string `json:"caption"`
+	TakenAt   *string `json:"taken_at"`
+	IsPrimary *bool   `json:"is_primary"`
+}
+
+// UpdateTowerPhoto updates the caption, capture date or primary flag of a tower photo. If-Match
+// must carry the tower's ETag.
+func UpdateTowerPhoto(c *gin.Context) {
+	photo, ok := findTowerPhoto(c)
+	if !ok {
+		return
+	}
+	tower, ok := loadTowerVersion(c, photo.TowerID)
+	if !ok {
+		return
+	}
+
+	var input UpdateTowerPhotoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.Caption != nil {
+		photo.Caption = *input.Caption
+	}
+	if input.TakenAt != nil {
+		takenAt, err := parseTakenAt(*input.TakenAt)
+		if err != nil {
+			helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		photo.TakenAt = takenAt
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := touchTower(tx, tower.ID, tower.Version); err != nil {
+			return err
+		}
+		if input.IsPrimary != nil && *input.IsPrimary {
+			if err := tx.Model(&models.TowerPhoto{}).Where("tower_id = ?", photo.TowerID).Update("is_primary", false).Error; err != nil {
+				return err
+			}
+			photo.IsPrimary = true
+		} else if input.IsPrimary != nil {
+			photo.IsPrimary = false
+		}
+		if err := tx.Save(photo).Error; err != nil {
+			return err
+		}
+		return syncPrimaryPhoto(tx, photo.TowerID)
+	})
+	if errors.Is(err, errVersionConflict) {
+		sendTowerConflict(c, tower.ID)
+		return
+	}
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to update tower photo: "+err.Error())
+		return
+	}
+
+	database.DB.First(photo, photo.ID)
+	presentTowerPhoto(c.Request.Context(), photo)
+	helper.SetETag(c, tower.Version+1)
+	helper.SendSuccessResponse(c, http.StatusOK, "Tower photo updated successfully", photo)
+}
+
+// ReorderTowerPhotosInput lists every photo ID of a tower in the desired display order
+type ReorderTowerPhotosInput struct {
+	PhotoIDs []uint `json:"photo_ids" binding:"required"`
+}
+
+// ReorderTowerPhotos changes the display order of a tower's photos. If-Match must carry the
+// tower's ETag.
+func ReorderTowerPhotos(c *gin.Context) {
+	towerID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid tower ID")
+		return
+	}
+	tower, ok := loadTowerVersion(c, towerID)
+	if !ok {
+		return
+	}
+
+	var input ReorderTowerPhotosInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var photos []models.TowerPhoto
+	if err := database.DB.Where("tower_id = ?", towerID).Find(&photos).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch tower photos")
+		return
+	}
+
+	// The new order must mention every photo of the tower exactly once
+	known := make(map[uint]bool, len(photos))
+	for _, photo := range photos {
+		known[photo.ID] = true
+	}
+	if len(input.PhotoIDs) != len(photos) {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "photo_ids must list every photo of the tower")
+		return
+	}
+	for _, id := range input.PhotoIDs {
+		if !known[id] {
+			helper.SendErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Photo %d does not belong to this tower or is listed twice", id))
+			return
+		}
+		delete(known, id)
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range input.PhotoIDs {
+			if err := tx.Model(&models.TowerPhoto{}).Where("id = ?", id).Update("sort_order", i).Error; err != nil {
+				return err
+			}
+		}
+		return touchTower(tx, tower.ID, tower.Version)
+	})
+	if errors.Is(err, errVersionConflict) {
+		sendTowerConflict(c, tower.ID)
+		return
+	}
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to reorder tower photos")
+		return
+	}
+
+	database.DB.Scopes(orderedPhotos).Where("tower_id = ?", towerID).Find(&photos)
+	for i := range photos {
+		presentTowerPhoto(c.Request.Context(), &photos[i])
+	}
+	helper.SetETag(c, tower.Version+1)
+	helper.SendSuccessResponse(c, http.StatusOK, "Tower photos reordered successfully", photos)
+}
+
+// DeleteTowerPhoto removes a photo and its renditions. If it was the primary photo,
+// the next photo in display order takes its place. If-Match must carry the tower's ETag.
+func DeleteTowerPhoto(c *gin.Context) {
+	photo, ok := findTowerPhoto(c)
+	if !ok {
+		return
+	}
+	tower, ok := loadTowerVersion(c, photo.TowerID)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := touchTower(tx, tower.ID, tower.Version); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(photo).Error; err != nil {
+			return err
+		}
+		return syncPrimaryPhoto(tx, photo.TowerID)
+	})
+	if errors.Is(err, errVersionConflict) {
+		sendTowerConflict(c, tower.ID)
+		return
+	}
+	if err != nil {
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to delete tower photo")
+		return
+	}
+
+	removePhotoFiles(c.Request.Context(), photo.URL, photo.MediumURL, photo.ThumbnailURL)
+
+	if err := createTowerEvent(c, photo.TowerID, "PhotoUpdate", "A photo was removed from the tower.", gin.H{
+		"photo_id":  photo.ID,
+		"photo_url": photo.URL,
+		"caption":   photo.Caption,
+	}, nil); err != nil {
+		helper.Logger(c).Error("Failed to create tower event for photo removal", "tower_id", photo.TowerID, "error", err)
+	}
+
+	helper.SetETag(c, tower.Version+1)
+	helper.SendSuccessResponse(c, http.StatusOK, "Tower photo deleted successfully", nil)
+}
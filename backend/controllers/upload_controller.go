@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/storage"
+)
+
+// presentTowerPhoto replaces the stored URLs of a photo with the ones clients should use (see storage.PresentURL)
+func presentTowerPhoto(ctx context.Context, photo *models.TowerPhoto) {
+	photo.URL = storage.PresentURL(ctx, photo.URL)
+	photo.MediumURL = storage.PresentURL(ctx, photo.MediumURL)
+	photo.ThumbnailURL = storage.PresentURL(ctx, photo.ThumbnailURL)
+}
+
+// presentTower replaces the stored photo URLs of a tower with the ones clients should use
+func presentTower(ctx context.Context, tower *models.Tower) {
+	tower.PhotoURL = storage.PresentURL(ctx, tower.PhotoURL)
+	for i := range tower.Photos {
+		presentTowerPhoto(ctx, &tower.Photos[i])
+	}
+}
+
+// presentChangeRequest replaces the stored URLs of the photo of a change request with the ones clients should use
+func presentChangeRequest(ctx context.Context, request *models.ChangeRequest) {
+	if request.Photo == nil {
+		return
+	}
+	request.Photo.URL = storage.PresentURL(ctx, request.Photo.URL)
+	request.Photo.MediumURL = storage.PresentURL(ctx, request.Photo.MediumURL)
+	request.Photo.ThumbnailURL = storage.PresentURL(ctx, request.Photo.ThumbnailURL)
+}
+
+// ServeUpload serves a stored upload. When signed URLs are enabled, every backend must verify the
+// signature: those that hand out URLs of their own, like S3, are refused. Otherwise non-local
+// backends are proxied so that URLs stored before switching backends keep working.
+func ServeUpload(c *gin.Context) {
+	key, ok := storage.CleanKey(c.Param("filepath"))
+	if !ok || strings.HasPrefix(key, storage.QuarantinePrefix) {
+		helper.SendErrorResponse(c, http.StatusNotFound, "File not found")
+		return
+	}
+
+	if storage.SignedURLs {
+		verifier, ok := storage.Default.(storage.SignatureVerifier)
+		if !ok || !verifier.VerifySignature(key, c.Query("expires"), c.Query("signature")) {
+			helper.SendErrorResponse(c, http.StatusForbidden, "Invalid or expired signature")
+			return
+		}
+	}
+
+	if local, isLocal := storage.Default.(*storage.LocalStorage); isLocal {
+		if exists, _ := local.Exists(c.Request.Context(), key); !exists {
+			helper.SendErrorResponse(c, http.StatusNotFound, "File not found")
+			return
+		}
+		c.File(local.Path(key))
+		return
+	}
+
+	reader, err := storage.Default.Get(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		helper.SendErrorResponse(c, http.StatusNotFound, "File not found")
+		return
+	} else if err != nil {
+		helper.SendErrorResponse(c, http.StatusBadGateway, "Failed to read file from storage")
+		return
+	}
+	defer reader.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" || strings.HasPrefix(contentType, "text/html") {
+		contentType = "application/octet-stream"
+	}
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", contentType)
+	io.Copy(c.Writer, reader)
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/jdeng/goheif" // Registers the "heic" image format
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/helper"
+)
+
+// multipartOverheadBytes is allowed on top of the file size for the other fields of an upload form
+const multipartOverheadBytes = 1 << 20
+
+// allowedImageFormats lists the image formats accepted for uploads, as named by image.DecodeConfig
+var allowedImageFormats = map[string]bool{
+	"jpeg": true,
+	"png":  true,
+	"gif":  true,
+	"webp": true,
+	"heic": true,
+}
+
+// UploadLimits bounds the size of image uploads
+type UploadLimits struct {
+	MaxBytes  int64 // Maximum size of the uploaded file
+	MaxPixels int   // Maximum width * height, checked before the image is decoded
+	MaxEdge   int   // Longest edge of the stored original; larger images are scaled down
+}
+
+// uploadLimits returns the configured upload limits
+func uploadLimits() UploadLimits {
+	cfg := config.Current.Upload
+	return UploadLimits{MaxBytes: cfg.MaxBytes, MaxPixels: cfg.MaxPixels, MaxEdge: cfg.MaxEdge}
+}
+
+// uploadError is returned for uploads rejected by validation
+type uploadError struct {
+	status  int // HTTP status to respond with
+	message string
+}
+
+func (e *uploadError) Error() string {
+	return e.message
+}
+
+// uploadErrorStatus returns the HTTP status for an error from the upload pipeline:
+// the status of a validation error, 500 for anything else.
+func uploadErrorStatus(err error) int {
+	var validationErr *uploadError
+	if errors.As(err, &validationErr) {
+		return validationErr.status
+	}
+	return http.StatusInternalServerError
+}
+
+// parseUploadForm caps the request body according to the upload limits and parses the multipart
+// form before any field is read. It responds with an error and returns false if parsing fails.
+func parseUploadForm(c *gin.Context) bool {
+	limits := uploadLimits()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBytes+multipartOverheadBytes)
+
+	err := c.Request.ParseMultipartForm(multipartOverheadBytes)
+	if err == nil || errors.Is(err, http.ErrNotMultipart) {
+		return true
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		helper.SendErrorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Upload exceeds the maximum size of %d bytes", limits.MaxBytes))
+	} else {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid multipart form")
+	}
+	return false
+}
+
+// readImageUpload reads an uploaded image into memory after checking it against limits. The content
+// type is sniffed from the data rather than trusted from the client, and the dimensions are read from
+// the image header so that oversized images (e.g. decompression bombs) are rejected before decoding.
+// It returns the raw data and the detected format.
+func readImageUpload(file *multipart.FileHeader, limits UploadLimits) ([]byte, string, error) {
+	tooLarge := &uploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("Photo exceeds the maximum size of %d bytes", limits.MaxBytes)}
+	if file.Size > limits.MaxBytes {
+		return nil, "", tooLarge
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, limits.MaxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if int64(len(data)) > limits.MaxBytes {
+		return nil, "", tooLarge
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !allowedImageFormats[format] {
+		return nil, "", &uploadError{http.StatusUnsupportedMediaType, "Unsupported image type, expected JPEG, PNG, GIF, WebP or HEIC"}
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, "", &uploadError{http.StatusBadRequest, "Invalid image dimensions"}
+	}
+	if config.Width*config.Height > limits.MaxPixels {
+		return nil, "", &uploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("Photo exceeds the maximum of %d pixels", limits.MaxPixels)}
+	}
+
+	return data, format, nil
+}
+
+// applyOrientation rotates and flips img according to an EXIF orientation value (1-8),
+// so that the image still displays upright once its metadata has been stripped.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	src := toRGBA(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 { // Orientations 5-8 swap width and height
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < height; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+width*4]
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				dx, dy = width-1-x, y
+			case 3: // Rotated 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // Mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // Transposed
+				dx, dy = y, x
+			case 6: // Rotated 90° clockwise
+				dx, dy = height-1-y, x
+			case 7: // Transversed
+				dx, dy = height-1-y, width-1-x
+			case 8: // Rotated 90° counter-clockwise
+				dx, dy = y, width-1-x
+			}
+			offset := dy*dst.Stride + dx*4
+			copy(dst.Pix[offset:offset+4], row[x*4:x*4+4])
+		}
+	}
+	return dst
+}
+
+// toRGBA returns img as an *image.RGBA with its origin at (0, 0), converting it if necessary
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	return rgba
+}
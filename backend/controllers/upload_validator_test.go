@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/jdeng/goheif/heif"
+	"github.com/jdeng/goheif/heif/bmff"
+)
+
+// orientationSample is a 3x2 image whose pixels have distinct red values 0-5, row by row
+func orientationSample() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(10, 20, 13, 22)) // Non-zero origin, as sub-images have
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			img.Set(10+x, 20+y, color.NRGBA{R: uint8(y*3 + x), A: 255})
+		}
+	}
+	return img
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// Expected red values of the result, row by row
+	tests := []struct {
+		orientation int
+		width       int
+		want        []uint8
+	}{
+		{1, 3, []uint8{0, 1, 2, 3, 4, 5}},
+		{2, 3, []uint8{2, 1, 0, 5, 4, 3}},
+		{3, 3, []uint8{5, 4, 3, 2, 1, 0}},
+		{4, 3, []uint8{3, 4, 5, 0, 1, 2}},
+		{5, 2, []uint8{0, 3, 1, 4, 2, 5}},
+		{6, 2, []uint8{3, 0, 4, 1, 5, 2}},
+		{7, 2, []uint8{5, 2, 4, 1, 3, 0}},
+		{8, 2, []uint8{2, 5, 1, 4, 0, 3}},
+	}
+	for _, tt := range tests {
+		got := applyOrientation(orientationSample(), tt.orientation)
+		bounds := got.Bounds()
+		if bounds.Dx() != tt.width || bounds.Dx()*bounds.Dy() != len(tt.want) {
+			t.Errorf("orientation %d: got %dx%d image", tt.orientation, bounds.Dx(), bounds.Dy())
+			continue
+		}
+		for i, want := range tt.want {
+			x, y := bounds.Min.X+i%tt.width, bounds.Min.Y+i/tt.width
+			if r, _, _, _ := got.At(x, y).RGBA(); uint8(r>>8) != want {
+				t.Errorf("orientation %d: pixel (%d, %d) = %d, want %d", tt.orientation, x, y, r>>8, want)
+			}
+		}
+	}
+}
+
+func TestResizeToFitKeepsAspectRatio(t *testing.T) {
+	got := resizeToFit(image.NewRGBA(image.Rect(0, 0, 4000, 3000)), 1024).Bounds()
+	if got.Dx() != 1024 || got.Dy() != 768 {
+		t.Errorf("got %dx%d, want 1024x768", got.Dx(), got.Dy())
+	}
+}
+
+// redRows returns the red values of img, row by row
+func redRows(img image.Image) [][]uint8 {
+	bounds := img.Bounds()
+	rows := make([][]uint8, bounds.Dy())
+	for y := range rows {
+		rows[y] = make([]uint8, bounds.Dx())
+		for x := range rows[y] {
+			r, _, _, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			rows[y][x] = uint8(r >> 8)
+		}
+	}
+	return rows
+}
+
+func TestHEIFOrientation(t *testing.T) {
+	// The transformations as the HEIF specification describes them: rotations counter-clockwise,
+	// then mirroring
+	rotate := func(rows [][]uint8) [][]uint8 {
+		rotated := make([][]uint8, len(rows[0]))
+		for y := range rotated {
+			rotated[y] = make([]uint8, len(rows))
+			for x := range rotated[y] {
+				rotated[y][x] = rows[x][len(rows[0])-1-y]
+			}
+		}
+		return rotated
+	}
+	mirrors := map[string]func(rows [][]uint8) [][]uint8{
+		"none": func(rows [][]uint8) [][]uint8 { return rows },
+		"top to bottom": func(rows [][]uint8) [][]uint8 {
+			mirrored := make([][]uint8, len(rows))
+			for y := range rows {
+				mirrored[len(rows)-1-y] = rows[y]
+			}
+			return mirrored
+		},
+		"left to right": func(rows [][]uint8) [][]uint8 {
+			mirrored := make([][]uint8, len(rows))
+			for y, row := range rows {
+				mirrored[y] = make([]uint8, len(row))
+				for x := range row {
+					mirrored[y][len(row)-1-x] = row[x]
+				}
+			}
+			return mirrored
+		},
+	}
+	mirrorProperties := map[string]bmff.Box{
+		"top to bottom": &bmff.ImageMirror{Mirror: bmff.MirrorVertical},
+		"left to right": &bmff.ImageMirror{Mirror: bmff.MirrorHorizontal},
+	}
+
+	for name, mirror := range mirrors {
+		for rotations := 0; rotations < 4; rotations++ {
+			item := &heif.Item{Properties: []bmff.Box{&bmff.ImageSpatialExtentsProperty{}}}
+			if rotations > 0 {
+				item.Properties = append(item.Properties, &bmff.ImageRotation{Angle: uint8(rotations)})
+			}
+			if property, ok := mirrorProperties[name]; ok {
+				item.Properties = append(item.Properties, property)
+			}
+
+			want := redRows(orientationSample())
+			for i := 0; i < rotations; i++ {
+				want = rotate(want)
+			}
+			want = mirror(want)
+			got := redRows(applyOrientation(orientationSample(), heifOrientation(item)))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%d rotations, mirrored %s: got %v, want %v", rotations, name, got, want)
+			}
+		}
+	}
+}
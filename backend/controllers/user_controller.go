@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+)
+
+// GetUsers lists every user account
+func GetUsers(c *gin.Context) {
+	var users []models.User
+	if err := database.DB.Order("id").Find(&users).Error; err != nil {
+		helper.Logger(c).Error("Failed to fetch users", "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+	helper.SendSuccessResponse(c, http.StatusOK, "Users fetched successfully", users)
+}
+
+type SetUserProviderInput struct {
+	ProviderID *uint `json:"provider_id"` // Null turns the account into a staff account
+}
+
+// SetUserProvider links a user to a provider, turning it into an operator account, or unlinks it
+func SetUserProvider(c *gin.Context) {
+	var input SetUserProviderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+	if user.ID == c.MustGet("user_id").(uint) && input.ProviderID != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "You cannot link your own account to a provider")
+		return
+	}
+	if input.ProviderID != nil {
+		var provider models.Provider
+		if err := database.DB.First(&provider, *input.ProviderID).Error; err != nil {
+			helper.SendErrorResponse(c, http.StatusBadRequest, "Provider not found")
+			return
+		}
+	}
+
+	if err := database.DB.Model(&user).Update("provider_id", input.ProviderID).Error; err != nil {
+		helper.Logger(c).Error("Failed to update user provider", "target_user_id", user.ID, "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+	helper.Logger(c).Info("User provider changed", "target_user_id", user.ID, "provider_id", input.ProviderID)
+
+	helper.SendSuccessResponse(c, http.StatusOK, "User updated successfully", user)
+}
+
+type SetUserApproverInput struct {
+	IsApprover *bool `json:"is_approver" binding:"required"`
+}
+
+// SetUserApprover grants or revokes the right to review change requests in four-eyes mode. Only
+// approvers may change it, whether or not that mode is enabled, so that approver rights can be
+// handed out ahead of enabling it. Nobody may change their own.
+func SetUserApprover(c *gin.Context) {
+	var input SetUserApproverInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		helper.SendErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+	currentUserID := c.MustGet("user_id").(uint)
+	if user.ID == currentUserID {
+		helper.SendErrorResponse(c, http.StatusBadRequest, "You cannot change your own approver rights")
+		return
+	}
+	var currentUser models.User
+	if err := database.DB.Select("id", "is_approver").First(&currentUser, currentUserID).Error; err != nil || !currentUser.IsApprover {
+		helper.Logger(c).Warn("Refused approver rights change by a non-approver", "target_user_id", user.ID)
+		helper.SendErrorResponse(c, http.StatusForbidden, "Only approvers can grant approver rights")
+		return
+	}
+
+	if err := database.DB.Model(&user).Update("is_approver", *input.IsApprover).Error; err != nil {
+		helper.Logger(c).Error("Failed to update user approver rights", "target_user_id", user.ID, "error", err)
+		helper.SendErrorResponse(c, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+	helper.Logger(c).Info("User approver rights changed", "target_user_id", user.ID, "is_approver", user.IsApprover)
+
+	helper.SendSuccessResponse(c, http.StatusOK, "User updated successfully", user)
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+// signedInAs stands in for AuthMiddleware, signing requests in as the user with the given ID
+func signedInAs(userID uint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user_id", userID)
+	}
+}
+
+// createUser adds a staff account
+func createUser(t *testing.T, email string, isApprover bool) models.User {
+	user := models.User{Name: email, Email: email, IsApprover: isApprover}
+	require.NoError(t, database.DB.Create(&user).Error)
+	return user
+}
+
+func TestSetUserApprover(t *testing.T) {
+	for _, fourEyes := range []string{"false", "true"} {
+		t.Run("four-eyes "+fourEyes, func(t *testing.T) {
+			testutil.Setup(t, map[string]string{"FOUR_EYES_APPROVAL": fourEyes})
+			approver := createUser(t, "approver@example.com", true)
+			staff := createUser(t, "staff@example.com", false)
+			target := createUser(t, "target@example.com", false)
+
+			setApprover := func(caller, target uint, isApprover bool) int {
+				router := gin.New()
+				router.PUT("/api/admin/users/:id/approver", signedInAs(caller), SetUserApprover)
+				body := fmt.Sprintf(`{"is_approver": %t}`, isApprover)
+				req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/admin/users/%d/approver", target), strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				return w.Code
+			}
+			isApprover := func(id uint) bool {
+				var user models.User
+				require.NoError(t, database.DB.First(&user, id).Error)
+				return user.IsApprover
+			}
+
+			assert.Equal(t, http.StatusForbidden, setApprover(staff.ID, target.ID, true), "staff without approver rights")
+			assert.False(t, isApprover(target.ID))
+
+			assert.Equal(t, http.StatusOK, setApprover(approver.ID, target.ID, true))
+			assert.True(t, isApprover(target.ID))
+			assert.Equal(t, http.StatusBadRequest, setApprover(approver.ID, approver.ID, false), "own rights")
+			assert.Equal(t, http.StatusOK, setApprover(target.ID, approver.ID, false), "new approvers may revoke others")
+			assert.False(t, isApprover(approver.ID))
+		})
+	}
+}
@@ -2,54 +2,81 @@ package database
 
 import (
 	"log"
-	"os"
+	"log/slog"
+	"net/url"
+	"time"
 
-	"github.com/user/tower-tracker-bima/backend/models"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
-func ConnectDatabase() {
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "backend/tower_tracker.db" // Default for local development
+// Supported values of DB_DRIVER
+const (
+	DriverSQLite   = config.DriverSQLite
+	DriverPostgres = config.DriverPostgres
+)
+
+// Driver is the database driver selected by ConnectDatabase
+var Driver string
+
+// openDialector opens the database configured in config.Current
+func openDialector() (gorm.Dialector, string) {
+	cfg := config.Current.Database
+	Driver = cfg.Driver
+	if cfg.Driver == DriverPostgres {
+		return postgres.Open(cfg.DSN), redactDSN(cfg.DSN)
 	}
+	return sqlite.Open(cfg.DSN), cfg.DSN
+}
 
-	log.Printf("Attempting to connect to database at: %s", dbPath)
-	database, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("Failed to connect to database at %s: %v", dbPath, err)
+// SQLitePath returns the database file when SQLite is configured, without connecting to it.
+// It reports false when PostgreSQL is configured instead.
+func SQLitePath() (string, bool) {
+	cfg := config.Current.Database
+	if cfg.Driver != DriverSQLite {
+		return "", false
 	}
+	return cfg.DSN, true
+}
 
-	err = database.AutoMigrate(&models.User{}, &models.Provider{}, &models.Tower{}, &models.BlankspotArea{}, &models.TowerEvent{})
+// redactDSN hides the password of a connection URL so that it can be logged
+func redactDSN(dsn string) string {
+	parsed, err := url.Parse(dsn)
+	if err != nil || parsed.User == nil {
+		return dsn
+	}
+	return parsed.Redacted()
+}
+
+func ConnectDatabase() {
+	dialector, target := openDialector()
+
+	log.Printf("Attempting to connect to %s database at: %s", Driver, target)
+	database, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.NewSlogLogger(slog.Default(), logger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  logger.Warn,
+			IgnoreRecordNotFoundError: true,
+		}),
+	})
 	if err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+		log.Fatalf("Failed to connect to database at %s: %v", target, err)
 	}
 
 	DB = database
-	log.Println("Database connection and migration successful.")
+	log.Println("Database connection successful.")
 }
 
-func SeedAdminUser() {
-	var user models.User
-	DB.Where("email = ?", "admin@example.com").First(&user)
-
-	if user.ID == 0 {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
-		if err != nil {
-			log.Fatalf("Failed to hash password: %v", err)
-		}
-
-		adminUser := models.User{
-			Email:    "admin@example.com",
-			Password: string(hashedPassword),
-		}
-		DB.Create(&adminUser)
-		log.Println("Admin user 'admin@example.com' seeded with password 'password'")
-	} else {
-		log.Println("Admin user already exists.")
+// Close closes the database connection
+func Close() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
 	}
+	return sqlDB.Close()
 }
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned, reversible schema change. Versions must be unique and increasing;
+// applied migrations are recorded in the schema_migrations table and must never be edited.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records an applied migration
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus describes whether a known migration has been applied
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// sortedMigrations returns the registered migrations ordered by version
+func sortedMigrations() []Migration {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return sorted
+}
+
+// appliedMigrations returns the applied migrations keyed by version
+func appliedMigrations(db *gorm.DB) (map[int]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// MigrationStatuses lists every known migration and when it was applied
+func MigrationStatuses(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range sortedMigrations() {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// PendingMigrations returns the migrations that have not been applied yet
+func PendingMigrations(db *gorm.DB) ([]Migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range sortedMigrations() {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// MigrateUp applies all pending migrations in version order, each in its own transaction
+func MigrateUp(db *gorm.DB) ([]Migration, error) {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		log.Printf("Applied migration %d (%s).", migration.Version, migration.Name)
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// MigrateDown rolls back the given number of most recently applied migrations
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := sortedMigrations()
+	var done []Migration
+	for i := len(sorted) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := sorted[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("rollback of migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		log.Printf("Rolled back migration %d (%s).", migration.Version, migration.Name)
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// EnsureSchemaCurrent fails when migrations are pending, so that the server never runs
+// against an outdated schema. With autoMigrate, pending migrations are applied instead.
+func EnsureSchemaCurrent(db *gorm.DB, autoMigrate bool) error {
+	if autoMigrate {
+		_, err := MigrateUp(db)
+		return err
+	}
+
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is behind by %d migration(s), starting with %d (%s); run \"migrate up\" first",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// CheckSchemaCurrent returns an error when migrations are pending. Unlike PendingMigrations it only
+// reads, so it is cheap enough for readiness probes.
+func CheckSchemaCurrent(db *gorm.DB) error {
+	var versions []int
+	if err := db.Model(&SchemaMigration{}).Pluck("version", &versions).Error; err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	applied := make(map[int]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+
+	pending := 0
+	for _, migration := range migrations {
+		if !applied[migration.Version] {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d migration(s) pending", pending)
+	}
+	return nil
+}
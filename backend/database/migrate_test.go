@@ -0,0 +1,64 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+func TestMigrateUpAndDown(t *testing.T) {
+	testutil.LoadConfig(t, nil)
+	db := testutil.OpenDatabase(t)
+
+	applied, err := database.MigrateUp(db)
+	require.NoError(t, err)
+	require.NotEmpty(t, applied)
+	require.NoError(t, database.CheckSchemaCurrent(db))
+
+	statuses, err := database.MigrationStatuses(db)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, "migration %d %s", status.Version, status.Name)
+	}
+	for _, table := range []interface{}{&models.User{}, &models.Provider{}, &models.Tower{}, &models.BlankspotArea{}, &models.TowerPhoto{}} {
+		assert.True(t, db.Migrator().HasTable(table), "%T", table)
+	}
+	assert.Equal(t, database.Driver == database.DriverPostgres, db.Migrator().HasColumn("towers", "geom"), "geometry columns exist only on PostgreSQL")
+
+	again, err := database.MigrateUp(db)
+	require.NoError(t, err)
+	assert.Empty(t, again, "applied migrations must not run again")
+
+	// Every migration can be reverted and applied again
+	reverted, err := database.MigrateDown(db, len(applied))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(applied))
+	assert.False(t, db.Migrator().HasTable(&models.Tower{}))
+	assert.Error(t, database.CheckSchemaCurrent(db))
+
+	reapplied, err := database.MigrateUp(db)
+	require.NoError(t, err)
+	assert.Len(t, reapplied, len(applied))
+	require.NoError(t, database.CheckSchemaCurrent(db))
+}
+
+func TestMigrateDownSteps(t *testing.T) {
+	testutil.LoadConfig(t, nil)
+	db := testutil.OpenDatabase(t)
+	applied, err := database.MigrateUp(db)
+	require.NoError(t, err)
+
+	reverted, err := database.MigrateDown(db, 1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, applied[len(applied)-1].Version, reverted[0].Version, "the latest migration is reverted first")
+
+	pending, err := database.PendingMigrations(db)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, reverted[0].Version, pending[0].Version)
+}
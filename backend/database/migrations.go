@@ -0,0 +1,368 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migrations lists every schema migration. Append new migrations with the next version;
+// never edit or reorder applied ones. Migrations declare their own copies of the models
+// so that later changes to the models package do not alter what an old migration does.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline_schema",
+		Up:      baselineUp,
+		Down:    baselineDown,
+	},
+	{
+		Version: 2,
+		Name:    "postgis_geometry",
+		Up: func(tx *gorm.DB) error {
+			return execOnPostgres(tx, postGISUpStatements)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execOnPostgres(tx, postGISDownStatements)
+		},
+	},
+	{
+		Version: 3,
+		Name:    "backfill_tower_photos",
+		Up:      backfillTowerPhotosUp,
+		Down:    backfillTowerPhotosDown,
+	},
+	{
+		Version: 4,
+		Name:    "login_security",
+		Up:      loginSecurityUp,
+		Down:    loginSecurityDown,
+	},
+	{
+		Version: 5,
+		Name:    "password_reset_tokens",
+		Up: func(tx *gorm.DB) error {
+			type PasswordResetToken struct {
+				ID        uint   `gorm:"primarykey"`
+				UserID    uint   `gorm:"index"`
+				TokenHash string `gorm:"uniqueIndex"`
+				ExpiresAt time.Time
+				UsedAt    *time.Time
+				CreatedAt time.Time
+			}
+			return tx.AutoMigrate(&PasswordResetToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("password_reset_tokens")
+		},
+	},
+	{
+		Version: 6,
+		Name:    "api_keys",
+		Up: func(tx *gorm.DB) error {
+			type APIKey struct {
+				gorm.Model
+				UserID     uint `gorm:"index"`
+				Name       string
+				Prefix     string `gorm:"uniqueIndex"`
+				KeyHash    string
+				Scopes     string
+				ExpiresAt  *time.Time
+				LastUsedAt *time.Time
+				LastUsedIP string
+			}
+			return tx.AutoMigrate(&APIKey{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("api_keys")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "provider_users_and_change_requests",
+		Up:      providerUsersUp,
+		Down:    providerUsersDown,
+	},
+	{
+		Version: 8,
+		Name:    "user_approvers",
+		Up: func(tx *gorm.DB) error {
+			type User struct {
+				IsApprover bool `gorm:"not null;default:false"`
+			}
+			return tx.Table("users").Migrator().AddColumn(&User{}, "IsApprover")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "users", "is_approver")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "user_oidc_subject",
+		Up: func(tx *gorm.DB) error {
+			type User struct {
+				OIDCSubject *string `gorm:"column:oidc_subject;uniqueIndex"`
+			}
+			if err := tx.Table("users").Migrator().AddColumn(&User{}, "OIDCSubject"); err != nil {
+				return err
+			}
+			return tx.Table("users").Migrator().CreateIndex(&User{}, "OIDCSubject")
+		},
+		Down: func(tx *gorm.DB) error {
+			type User struct {
+				OIDCSubject *string `gorm:"column:oidc_subject;uniqueIndex"`
+			}
+			if err := tx.Table("users").Migrator().DropIndex(&User{}, "OIDCSubject"); err != nil {
+				return err
+			}
+			return dropColumns(tx, "users", "oidc_subject")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "resource_versions",
+		Up: func(tx *gorm.DB) error {
+			type Versioned struct {
+				Version uint `gorm:"not null;default:1"`
+			}
+			for _, table := range versionedTables {
+				if err := tx.Table(table).Migrator().AddColumn(&Versioned{}, "Version"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, table := range versionedTables {
+				if err := dropColumns(tx, table, "version"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 11,
+		Name:    "change_request_base_version",
+		Up: func(tx *gorm.DB) error {
+			type ChangeRequest struct {
+				BaseVersion uint `gorm:"not null;default:0"`
+			}
+			return tx.Table("change_requests").Migrator().AddColumn(&ChangeRequest{}, "BaseVersion")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "change_requests", "base_version")
+		},
+	},
+	{
+		Version: 12,
+		Name:    "user_password_changed_at",
+		Up: func(tx *gorm.DB) error {
+			type User struct {
+				PasswordChangedAt *time.Time
+			}
+			return tx.Table("users").Migrator().AddColumn(&User{}, "PasswordChangedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, "users", "password_changed_at")
+		},
+	},
+}
+
+// versionedTables are the tables whose rows carry a version for optimistic concurrency control
+var versionedTables = []string{"towers", "providers", "blankspot_areas"}
+
+// baselineUp creates the schema as it was when versioned migrations were introduced. It uses
+// AutoMigrate so that databases created by the former AutoMigrate-on-boot adopt it unchanged.
+func baselineUp(tx *gorm.DB) error {
+	type User struct {
+		gorm.Model
+		Name     string
+		Email    string `gorm:"unique"`
+		Password string
+	}
+	type Provider struct {
+		gorm.Model
+		Name    string `gorm:"unique"`
+		Address string
+	}
+	type Tower struct {
+		gorm.Model
+		PhotoURL  string
+		Latitude  float64
+		Longitude float64
+		Kelurahan string
+		Kecamatan string
+		Address   string
+		Tinggi    float64
+		Tipe      string
+		Status    string      `gorm:"default:'active'"`
+		Providers []*Provider `gorm:"many2many:provider_towers;"`
+	}
+	type BlankspotArea struct {
+		gorm.Model
+		Name        string `gorm:"unique"`
+		Kelurahan   string
+		Coordinates string `gorm:"type:text"`
+		Type        string
+		Color       string
+	}
+	type TowerEvent struct {
+		ID          uint   `gorm:"primaryKey"`
+		TowerID     uint   `gorm:"index"`
+		EventType   string `gorm:"type:varchar(50)"`
+		Timestamp   time.Time
+		Description string `gorm:"type:text"`
+		OldData     string `gorm:"type:jsonb"`
+		NewData     string `gorm:"type:jsonb"`
+		UserID      uint
+		CreatedAt   time.Time
+		UpdatedAt   time.Time
+		DeletedAt   gorm.DeletedAt `gorm:"index"`
+	}
+	type TowerPhoto struct {
+		gorm.Model
+		TowerID           uint `gorm:"index"`
+		URL               string
+		MediumURL         string
+		ThumbnailURL      string
+		Caption           string
+		TakenAt           *time.Time
+		Latitude          *float64
+		Longitude         *float64
+		DistanceFromTower *float64
+		FarFromTower      bool
+		UploadedBy        uint
+		IsPrimary         bool
+		SortOrder         int
+	}
+
+	return tx.AutoMigrate(&User{}, &Provider{}, &Tower{}, &BlankspotArea{}, &TowerEvent{}, &TowerPhoto{})
+}
+
+func baselineDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("provider_towers", "tower_photos", "tower_events", "blankspot_areas", "towers", "providers", "users")
+}
+
+// execOnPostgres runs the statements on PostgreSQL and does nothing on other databases
+func execOnPostgres(tx *gorm.DB, statements []string) error {
+	if tx.Dialector.Name() != DriverPostgres {
+		return nil
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillTowerPhotosUp creates a primary tower photo for towers that only have the legacy PhotoURL set,
+// so that every existing photo shows up in the tower's photo gallery.
+func backfillTowerPhotosUp(tx *gorm.DB) error {
+	return tx.Exec(`INSERT INTO tower_photos (created_at, updated_at, tower_id, url, medium_url, thumbnail_url, caption, far_from_tower, uploaded_by, is_primary, sort_order)
+		SELECT ?, ?, id, photo_url, photo_url, photo_url, '', false, 0, true, 0 FROM towers
+		WHERE photo_url <> '' AND NOT EXISTS (SELECT 1 FROM tower_photos WHERE tower_photos.tower_id = towers.id)`,
+		time.Now(), time.Now()).Error
+}
+
+// backfillTowerPhotosDown removes the photos created by backfillTowerPhotosUp, recognizable by
+// having no uploader and no renditions of their own.
+func backfillTowerPhotosDown(tx *gorm.DB) error {
+	return tx.Exec(`DELETE FROM tower_photos WHERE uploaded_by = 0 AND url = medium_url AND url = thumbnail_url`).Error
+}
+
+// loginSecurityUp adds the failed login counter and lockout of users, the login history and security alerts
+func loginSecurityUp(tx *gorm.DB) error {
+	type User struct {
+		FailedLoginAttempts int `gorm:"not null;default:0"`
+		LockedUntil         *time.Time
+	}
+	type LoginEvent struct {
+		ID            uint   `gorm:"primarykey"`
+		UserID        *uint  `gorm:"index"`
+		Email         string `gorm:"index"`
+		IP            string
+		UserAgent     string
+		Success       bool
+		FailureReason string
+		CreatedAt     time.Time `gorm:"index"`
+	}
+	type SecurityAlert struct {
+		ID             uint `gorm:"primarykey"`
+		UserID         uint `gorm:"index"`
+		Type           string
+		Message        string
+		AcknowledgedAt *time.Time
+		AcknowledgedBy *uint
+		CreatedAt      time.Time `gorm:"index"`
+	}
+
+	for _, column := range []string{"FailedLoginAttempts", "LockedUntil"} {
+		if err := tx.Table("users").Migrator().AddColumn(&User{}, column); err != nil {
+			return err
+		}
+	}
+	return tx.AutoMigrate(&LoginEvent{}, &SecurityAlert{})
+}
+
+func loginSecurityDown(tx *gorm.DB) error {
+	if err := tx.Migrator().DropTable("security_alerts", "login_events"); err != nil {
+		return err
+	}
+	return dropColumns(tx, "users", "failed_login_attempts", "locked_until")
+}
+
+// providerUsersUp links users to providers and adds the change requests their edits are queued in
+func providerUsersUp(tx *gorm.DB) error {
+	type User struct {
+		ProviderID *uint `gorm:"index"`
+	}
+	type ChangeRequest struct {
+		gorm.Model
+		TowerID         uint   `gorm:"index"`
+		Type            string `gorm:"type:varchar(50)"`
+		Status          string `gorm:"type:varchar(20);index"`
+		OldData         string `gorm:"type:jsonb"`
+		NewData         string `gorm:"type:jsonb"`
+		Photo           string
+		RequestedBy     uint `gorm:"index"`
+		ReviewedBy      *uint
+		ReviewedAt      *time.Time
+		RejectionReason string
+	}
+
+	if err := tx.Table("users").Migrator().AddColumn(&User{}, "ProviderID"); err != nil {
+		return err
+	}
+	if err := tx.Table("users").Migrator().CreateIndex(&User{}, "ProviderID"); err != nil {
+		return err
+	}
+	return tx.AutoMigrate(&ChangeRequest{})
+}
+
+func providerUsersDown(tx *gorm.DB) error {
+	type User struct {
+		ProviderID *uint `gorm:"index"`
+	}
+
+	if err := tx.Migrator().DropTable("change_requests"); err != nil {
+		return err
+	}
+	if err := tx.Table("users").Migrator().DropIndex(&User{}, "ProviderID"); err != nil {
+		return err
+	}
+	return dropColumns(tx, "users", "provider_id")
+}
+
+// dropColumns removes columns from a table in place. The SQLite migrator of GORM drops columns by
+// recreating the table, which loses its indexes; SQLite supports DROP COLUMN itself since 3.35.
+func dropColumns(tx *gorm.DB, table string, columns ...string) error {
+	for _, column := range columns {
+		if err := tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: table}, clause.Column{Name: column}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package database
+
+// postGISUpStatements add PostGIS geometry columns next to the portable columns the models use.
+// Both are generated from those columns, so the application keeps writing latitude/longitude and
+// the JSON coordinates while spatial queries can use the indexed geometries.
+var postGISUpStatements = []string{
+	`CREATE EXTENSION IF NOT EXISTS postgis`,
+
+	`ALTER TABLE towers ADD COLUMN IF NOT EXISTS geom geometry(Point, 4326)
+		GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_towers_geom ON towers USING GIST (geom)`,
+
+	// Blankspot coordinates are stored as a JSON array of [lat, lon] pairs. Invalid or
+	// degenerate polygons yield NULL instead of failing the write.
+	`CREATE OR REPLACE FUNCTION blankspot_polygon(coordinates text) RETURNS geometry(Polygon, 4326)
+	LANGUAGE plpgsql IMMUTABLE AS $$
+	DECLARE
+		ring geometry;
+	BEGIN
+		SELECT ST_MakeLine(ST_MakePoint((point->>1)::float8, (point->>0)::float8) ORDER BY position)
+		  INTO ring
+		  FROM jsonb_array_elements(coordinates::jsonb) WITH ORDINALITY AS p(point, position);
+		IF ring IS NULL OR ST_NPoints(ring) < 3 THEN
+			RETURN NULL;
+		END IF;
+		IF NOT ST_Equals(ST_StartPoint(ring), ST_EndPoint(ring)) THEN
+			ring := ST_AddPoint(ring, ST_StartPoint(ring));
+		END IF;
+		RETURN ST_SetSRID(ST_MakePolygon(ring), 4326);
+	EXCEPTION WHEN others THEN
+		RETURN NULL;
+	END;
+	$$`,
+	`ALTER TABLE blankspot_areas ADD COLUMN IF NOT EXISTS geom geometry(Polygon, 4326)
+		GENERATED ALWAYS AS (blankspot_polygon(coordinates)) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_blankspot_areas_geom ON blankspot_areas USING GIST (geom)`,
+}
+
+// postGISDownStatements remove the geometry columns again. The extension itself is left
+// installed, as other schemas in the database may depend on it.
+var postGISDownStatements = []string{
+	`DROP INDEX IF EXISTS idx_blankspot_areas_geom`,
+	`ALTER TABLE blankspot_areas DROP COLUMN IF EXISTS geom`,
+	`DROP FUNCTION IF EXISTS blankspot_polygon(text)`,
+	`DROP INDEX IF EXISTS idx_towers_geom`,
+	`ALTER TABLE towers DROP COLUMN IF EXISTS geom`,
+}
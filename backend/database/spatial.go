@@ -0,0 +1,64 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+)
+
+// Spatial queries run in the database on PostgreSQL/PostGIS and fall back to
+// computing the result in Go on SQLite.
+
+// TowerIDsWithinRadius returns the IDs of towers within radius meters of the given point
+func TowerIDsWithinRadius(lat, lon, radius float64) ([]uint, error) {
+	ids := []uint{}
+	if Driver == DriverPostgres {
+		err := DB.Model(&models.Tower{}).
+			Where("ST_DWithin(geom::geography, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, ?)", lon, lat, radius).
+			Pluck("id", &ids).Error
+		return ids, err
+	}
+
+	var towers []models.Tower
+	if err := DB.Select("id", "latitude", "longitude").Find(&towers).Error; err != nil {
+		return nil, err
+	}
+	for _, tower := range towers {
+		if helper.DistanceMeters(lat, lon, tower.Latitude, tower.Longitude) <= radius {
+			ids = append(ids, tower.ID)
+		}
+	}
+	return ids, nil
+}
+
+// TowerIDsInBlankspot returns the IDs of towers located inside a blankspot area
+func TowerIDsInBlankspot(area models.BlankspotArea) ([]uint, error) {
+	ids := []uint{}
+	if Driver == DriverPostgres {
+		err := DB.Model(&models.Tower{}).
+			Where("ST_Contains((SELECT geom FROM blankspot_areas WHERE id = ?), towers.geom)", area.ID).
+			Pluck("id", &ids).Error
+		return ids, err
+	}
+
+	var polygon [][]float64
+	if err := json.Unmarshal([]byte(area.Coordinates), &polygon); err != nil {
+		return nil, fmt.Errorf("invalid blankspot coordinates: %w", err)
+	}
+	if len(polygon) < 3 {
+		return ids, nil
+	}
+
+	var towers []models.Tower
+	if err := DB.Select("id", "latitude", "longitude").Find(&towers).Error; err != nil {
+		return nil, err
+	}
+	for _, tower := range towers {
+		if helper.PointInPolygon(tower.Latitude, tower.Longitude, polygon) {
+			ids = append(ids, tower.ID)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,77 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+// createTowers adds towers around a point in Jakarta: one at the point, one about 500 m north of
+// it and one about 5 km east
+func createTowers(t *testing.T) (center, north, east uint) {
+	towers := []models.Tower{
+		{Latitude: -6.2000, Longitude: 106.8000},
+		{Latitude: -6.1955, Longitude: 106.8000},
+		{Latitude: -6.2000, Longitude: 106.8450},
+	}
+	require.NoError(t, database.DB.Create(&towers).Error)
+	return towers[0].ID, towers[1].ID, towers[2].ID
+}
+
+func TestTowerIDsWithinRadius(t *testing.T) {
+	testutil.Setup(t, nil)
+	center, north, east := createTowers(t)
+
+	tests := []struct {
+		name   string
+		radius float64
+		want   []uint
+	}{
+		{"point only", 100, []uint{center}},
+		{"nearby", 1000, []uint{center, north}},
+		{"all", 10000, []uint{center, north, east}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := database.TowerIDsWithinRadius(-6.2000, 106.8000, tt.radius)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, ids)
+		})
+	}
+
+	ids, err := database.TowerIDsWithinRadius(-7, 110, 1000)
+	require.NoError(t, err)
+	assert.NotNil(t, ids, "no matches must give an empty list")
+	assert.Empty(t, ids)
+}
+
+func TestTowerIDsInBlankspot(t *testing.T) {
+	testutil.Setup(t, nil)
+	center, north, _ := createTowers(t)
+
+	tests := []struct {
+		name        string
+		coordinates string
+		want        []uint
+	}{
+		{"open ring", `[[-6.21,106.79],[-6.19,106.79],[-6.19,106.81],[-6.21,106.81]]`, []uint{center, north}},
+		{"closed ring", `[[-6.21,106.79],[-6.19,106.79],[-6.19,106.81],[-6.21,106.81],[-6.21,106.79]]`, []uint{center, north}},
+		{"triangle", `[[-6.21,106.79],[-6.198,106.80],[-6.21,106.81]]`, []uint{center}},
+		{"elsewhere", `[[-7.1,110.1],[-7.0,110.1],[-7.0,110.2]]`, []uint{}},
+		{"degenerate", `[[-6.21,106.79],[-6.19,106.81]]`, []uint{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			area := models.BlankspotArea{Name: tt.name, Coordinates: tt.coordinates}
+			require.NoError(t, database.DB.Create(&area).Error)
+
+			ids, err := database.TowerIDsInBlankspot(area)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, ids)
+		})
+	}
+}
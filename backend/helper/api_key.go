@@ -0,0 +1,45 @@
+package helper
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key, so that leaked keys are easy to recognize
+const APIKeyPrefix = "tt_"
+
+// GenerateAPIKey returns a new API key of the form "tt_<id>_<secret>", together with its public
+// prefix "tt_<id>" and the hash to store
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = APIKeyPrefix + hex.EncodeToString(id)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// APIKeyPrefixOf returns the public prefix of key, or false if key is not shaped like an API key
+func APIKeyPrefixOf(key string) (string, bool) {
+	prefix, secret, found := strings.Cut(strings.TrimPrefix(key, APIKeyPrefix), "_")
+	if !strings.HasPrefix(key, APIKeyPrefix) || !found || len(prefix) != 8 || secret == "" {
+		return "", false
+	}
+	return APIKeyPrefix + prefix, true
+}
+
+// HashAPIKey returns the hash stored for key. API keys are long random strings, so a fast hash is
+// as safe as bcrypt while keeping authentication cheap.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
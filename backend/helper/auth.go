@@ -1,18 +1,32 @@
 package helper
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/user/tower-tracker-bima/backend/config"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// NOTE: In a real application, this should be loaded from environment variables
-var JWT_SECRET = []byte(os.Getenv("JWT_SECRET_KEY"))
+// TokenClaims are the claims of the tokens issued to users
+type TokenClaims struct {
+	UserID uint `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// JWTSecret returns the application secret configured through JWT_SECRET_KEY. User tokens are
+// signed with JWTKeys instead.
+func JWTSecret() []byte {
+	return []byte(config.Current.Auth.JWTSecret)
+}
 
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), config.Current.Auth.BcryptCost)
 	return string(bytes), err
 }
 
@@ -21,12 +35,130 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// dummyPasswordHash is a hash with the configured bcrypt cost that no password is checked against
+// for real, created on first use
+var dummyPasswordHash struct {
+	sync.Mutex
+	cost int
+	hash []byte
+}
+
+// DummyPasswordCheck takes as long as checking password against a real hash. Logins refused before
+// the password is checked call it, so that response times do not reveal which emails have an
+// account or which accounts are locked.
+func DummyPasswordCheck(password string) {
+	cost := config.Current.Auth.BcryptCost
+	dummyPasswordHash.Lock()
+	if dummyPasswordHash.cost != cost {
+		hash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), cost)
+		if err != nil {
+			dummyPasswordHash.Unlock()
+			return
+		}
+		dummyPasswordHash.cost, dummyPasswordHash.hash = cost, hash
+	}
+	hash := dummyPasswordHash.hash
+	dummyPasswordHash.Unlock()
+
+	bcrypt.CompareHashAndPassword(hash, []byte(password))
+}
+
+// NeedsRehash reports whether hash was created with a bcrypt cost other than BCRYPT_COST, so that
+// it should be replaced by a new hash the next time the password is known
+func NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost != config.Current.Auth.BcryptCost
+}
+
+// GenerateJWT issues a token for the user, signed with the current key of JWTKeys
 func GenerateJWT(userID uint) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
+	cfg := config.Current.Auth
+	now := time.Now()
+	key := JWTKeys.Current
+	token := jwt.NewWithClaims(key.Method, TokenClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    cfg.JWTIssuer,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Audience:  jwt.ClaimStrings{cfg.JWTAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(cfg.TokenTTL)), // Token expires after JWT_TTL (default 24 hours)
+			ID:        uuid.NewString(),
+		},
 	})
+	token.Header["kid"] = key.ID
 
-	tokenString, err := token.SignedString(JWT_SECRET)
+	tokenString, err := token.SignedString(key.SignKey)
 	return tokenString, err
 }
+
+// legacyTokenTTL is the lifetime of the tokens issued before the key set, which carry no kid,
+// issuer, audience, issue time or ID
+const legacyTokenTTL = 24 * time.Hour
+
+// ParseJWT verifies a token issued by GenerateJWT against the key named by its kid header and
+// checks its issuer, audience, issue and expiry times and ID. Tokens without a kid are verified
+// as legacy tokens, so that introducing the key set logs nobody out.
+func ParseJWT(tokenString string) (*TokenClaims, error) {
+	unverified, _, err := jwt.NewParser().ParseUnverified(tokenString, &TokenClaims{})
+	if err != nil {
+		return nil, err
+	}
+	if _, hasKeyID := unverified.Header["kid"]; !hasKeyID {
+		return parseLegacyJWT(tokenString)
+	}
+
+	cfg := config.Current.Auth
+	claims := &TokenClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := JWTKeys.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.VerifyKey, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(cfg.JWTIssuer),
+		jwt.WithAudience(cfg.JWTAudience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" || claims.UserID == 0 {
+		return nil, errors.New("token lacks jti or user_id claim")
+	}
+	return claims, nil
+}
+
+// parseLegacyJWT verifies an HS256 token with only user_id and exp claims, as issued before the
+// key set, against the HMAC keys of JWTKeys. Such tokens expired at most legacyTokenTTL after they
+// were issued, so ones that would outlive it were not issued by an earlier release.
+func parseLegacyJWT(tokenString string) (*TokenClaims, error) {
+	secrets := jwt.VerificationKeySet{}
+	for _, key := range JWTKeys.keys {
+		if key.Method == jwt.SigningMethodHS256 {
+			secrets.Keys = append(secrets.Keys, key.VerifyKey)
+		}
+	}
+
+	claims := &TokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secrets, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims.ExpiresAt.After(time.Now().Add(legacyTokenTTL)) {
+		return nil, errors.New("token without kid expires too late to be a legacy token")
+	}
+	if claims.UserID == 0 {
+		return nil, errors.New("token lacks user_id claim")
+	}
+	return claims, nil
+}
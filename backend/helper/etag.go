@@ -0,0 +1,37 @@
+package helper
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag returns the strong entity tag of a resource at the given version
+func ETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// SetETag sets the ETag header of the response to the tag of the given version
+func SetETag(c *gin.Context, version uint) {
+	c.Header("ETag", ETag(version))
+}
+
+// IfMatch reports whether the request has an If-Match header and whether it matches a resource at
+// the given version. Weak tags never match, as If-Match uses the strong comparison (RFC 9110 13.1.1).
+func IfMatch(c *gin.Context, version uint) (present, matches bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return false, false
+	}
+	if header == "*" {
+		return true, true
+	}
+	etag := ETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == etag {
+			return true, true
+		}
+	}
+	return true, false
+}
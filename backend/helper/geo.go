@@ -0,0 +1,35 @@
+package helper
+
+import "math"
+
+// earthRadiusMeters is the mean radius of the earth used for great-circle distances
+const earthRadiusMeters = 6371000
+
+// DistanceMeters returns the great-circle (haversine) distance between two coordinates in meters.
+func DistanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// PointInPolygon reports whether the point lies inside polygon, given as [lat, lon] pairs.
+// The polygon does not need to be closed. Uses ray casting on plain coordinates, which is
+// accurate enough for the small areas tracked in the app.
+func PointInPolygon(lat, lon float64, polygon [][]float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		if len(polygon[i]) < 2 || len(polygon[j]) < 2 {
+			return false
+		}
+		latI, lonI := polygon[i][0], polygon[i][1]
+		latJ, lonJ := polygon[j][0], polygon[j][1]
+		if (latI > lat) != (latJ > lat) && lon < (lonJ-lonI)*(lat-latI)/(latJ-latI)+lonI {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,203 @@
+package helper
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/user/tower-tracker-bima/backend/config"
+)
+
+// minRSAKeyBits is the smallest RSA key accepted for signing tokens
+const minRSAKeyBits = 2048
+
+// JWTKey is a key of the token key set. Keys of earlier rotations may lack the private part.
+type JWTKey struct {
+	ID         string // Sent as the kid header of tokens signed with the key
+	Method     jwt.SigningMethod
+	SignKey    interface{} // []byte, *rsa.PrivateKey or ed25519.PrivateKey; nil for verification-only keys
+	VerifyKey  interface{} // []byte, *rsa.PublicKey or ed25519.PublicKey
+	Asymmetric bool        // Published in the JWK set
+}
+
+// JWTKeySet holds the key new tokens are signed with and every key tokens are still accepted from
+type JWTKeySet struct {
+	Current *JWTKey
+	keys    map[string]*JWTKey
+	order   []string
+}
+
+// JWTKeys is the key set tokens are signed and verified with, set up by InitJWTKeys
+var JWTKeys *JWTKeySet
+
+// InitJWTKeys loads JWTKeys from the auth section of config.Current
+func InitJWTKeys() error {
+	keys, err := LoadJWTKeySet(config.Current.Auth)
+	if err != nil {
+		return err
+	}
+	JWTKeys = keys
+	slog.Info("Loaded JWT keys", "alg", keys.Current.Method.Alg(), "kid", keys.Current.ID, "accepted_keys", len(keys.keys))
+	return nil
+}
+
+// LoadJWTKeySet builds the key set from JWT_SIGNING_KEY_FILE, or JWT_SECRET_KEY when it is empty,
+// followed by the keys of earlier rotations
+func LoadJWTKeySet(cfg config.AuthConfig) (*JWTKeySet, error) {
+	set := &JWTKeySet{keys: map[string]*JWTKey{}}
+
+	current := hmacJWTKey([]byte(cfg.JWTSecret))
+	if cfg.JWTSigningKeyFile != "" {
+		key, err := loadPEMJWTKey(cfg.JWTSigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if key.SignKey == nil {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE %s holds a public key, a private key is required", cfg.JWTSigningKeyFile)
+		}
+		current = key
+	}
+	set.add(current)
+	set.Current = current
+
+	for _, file := range cfg.JWTPreviousKeyFiles {
+		key, err := loadPEMJWTKey(file)
+		if err != nil {
+			return nil, err
+		}
+		set.add(key)
+	}
+	for _, secret := range cfg.JWTPreviousSecrets {
+		set.add(hmacJWTKey([]byte(secret)))
+	}
+	return set, nil
+}
+
+// add adds key unless a key with the same ID is already part of the set
+func (s *JWTKeySet) add(key *JWTKey) {
+	if _, exists := s.keys[key.ID]; exists {
+		return
+	}
+	s.keys[key.ID] = key
+	s.order = append(s.order, key.ID)
+}
+
+// Lookup returns the key with the given ID
+func (s *JWTKeySet) Lookup(id string) (*JWTKey, bool) {
+	key, ok := s.keys[id]
+	return key, ok
+}
+
+// JWKS returns the public keys of the set as a JWK set (RFC 7517). Secrets are never included.
+func (s *JWTKeySet) JWKS() map[string]interface{} {
+	keys := []map[string]string{}
+	for _, id := range s.order {
+		key := s.keys[id]
+		if !key.Asymmetric {
+			continue
+		}
+		jwk := publicJWK(key.VerifyKey)
+		jwk["kid"] = key.ID
+		jwk["alg"] = key.Method.Alg()
+		jwk["use"] = "sig"
+		keys = append(keys, jwk)
+	}
+	return map[string]interface{}{"keys": keys}
+}
+
+// hmacJWTKey returns an HS256 key. Its ID is derived from a hash of the secret, which does not reveal it.
+func hmacJWTKey(secret []byte) *JWTKey {
+	sum := sha256.Sum256(append([]byte("jwt-kid:"), secret...))
+	return &JWTKey{
+		ID:        "hs-" + hex.EncodeToString(sum[:8]),
+		Method:    jwt.SigningMethodHS256,
+		SignKey:   secret,
+		VerifyKey: secret,
+	}
+}
+
+// loadPEMJWTKey reads an RSA or Ed25519 key from a PEM file. Private keys may be PKCS #1 or
+// PKCS #8, public keys PKCS #1 or PKIX.
+func loadPEMJWTKey(file string) (*JWTKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", file)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	key := &JWTKey{Asymmetric: true}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.SignKey, key.VerifyKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.VerifyKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.SignKey, key.VerifyKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.VerifyKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("%s: only RSA and Ed25519 keys are supported", file)
+	}
+	if rsaKey, ok := key.VerifyKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("%s: RSA keys must have at least %d bits", file, minRSAKeyBits)
+	}
+	key.ID = jwkThumbprint(key.VerifyKey)
+	return key, nil
+}
+
+// publicJWK returns the public members of an RSA or Ed25519 public key as a JWK
+func publicJWK(key interface{}) map[string]string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(k),
+		}
+	}
+	return map[string]string{}
+}
+
+// jwkThumbprint computes the RFC 7638 thumbprint of a public key, used as its key ID
+func jwkThumbprint(key interface{}) string {
+	// The required members in lexicographic order; json.Marshal sorts map keys
+	jwk, _ := json.Marshal(publicJWK(key))
+	sum := sha256.Sum256(jwk)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
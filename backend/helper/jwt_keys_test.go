@@ -0,0 +1,215 @@
+package helper_test
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+// writePEM writes a PKCS #8 private key or, if public is set, its PKIX public key to a file and
+// returns its path
+func writePEM(t *testing.T, key interface{}, public bool) string {
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(key.(crypto.Signer).Public())
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	file, err := os.CreateTemp(t.TempDir(), "key-*.pem")
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, pem.Encode(file, block))
+	return file.Name()
+}
+
+// loadKeys loads the key set from a configuration with env
+func loadKeys(t *testing.T, env map[string]string) {
+	testutil.LoadConfig(t, env)
+	require.NoError(t, helper.InitJWTKeys())
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestJWTSignedWithCurrentKey(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys := map[string]string{
+		"HS256": "",
+		"RS256": writePEM(t, newRSAKey(t), false),
+		"EdDSA": writePEM(t, edKey, false),
+	}
+	for alg, file := range keys {
+		t.Run(alg, func(t *testing.T) {
+			loadKeys(t, map[string]string{"JWT_SIGNING_KEY_FILE": file})
+
+			token, err := helper.GenerateJWT(42)
+			require.NoError(t, err)
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, alg, parsed.Method.Alg())
+			assert.Equal(t, helper.JWTKeys.Current.ID, parsed.Header["kid"])
+
+			claims, err := helper.ParseJWT(token)
+			require.NoError(t, err)
+			assert.Equal(t, uint(42), claims.UserID)
+			assert.NotEmpty(t, claims.ID)
+		})
+	}
+}
+
+func TestJWTVerifiedWithRetiredKey(t *testing.T) {
+	oldKey := newRSAKey(t)
+	loadKeys(t, map[string]string{"JWT_SIGNING_KEY_FILE": writePEM(t, oldKey, false)})
+	rsaToken, err := helper.GenerateJWT(42)
+	require.NoError(t, err)
+	loadKeys(t, nil)
+	hmacToken, err := helper.GenerateJWT(42)
+	require.NoError(t, err)
+
+	// Rotated to a new key, with the old public key and the former secret still accepted
+	loadKeys(t, map[string]string{
+		"JWT_SIGNING_KEY_FILE":   writePEM(t, newRSAKey(t), false),
+		"JWT_PREVIOUS_KEY_FILES": writePEM(t, oldKey, true),
+		"JWT_PREVIOUS_SECRETS":   "test-secret-key",
+		"JWT_SECRET_KEY":         "another-secret-key",
+	})
+	for name, token := range map[string]string{"RS256": rsaToken, "HS256": hmacToken} {
+		claims, err := helper.ParseJWT(token)
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, uint(42), claims.UserID)
+		}
+	}
+
+	// Once the old keys are dropped, their tokens are refused
+	loadKeys(t, map[string]string{"JWT_SIGNING_KEY_FILE": writePEM(t, newRSAKey(t), false), "JWT_PREVIOUS_KEY_FILES": "", "JWT_PREVIOUS_SECRETS": ""})
+	_, err = helper.ParseJWT(rsaToken)
+	assert.ErrorContains(t, err, "unknown signing key")
+	_, err = helper.ParseJWT(hmacToken)
+	assert.ErrorContains(t, err, "unknown signing key")
+}
+
+// signClaims signs a token for user 42 with the claims GenerateJWT sets, the kid header and key
+func signClaims(method jwt.SigningMethod, kid string, key interface{}) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(method, helper.TokenClaims{
+		UserID: 42,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "tower-tracker",
+			Audience:  jwt.ClaimStrings{"tower-tracker-api"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			ID:        "token-1",
+		},
+	})
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+func TestJWTRejectsUnknownKeyID(t *testing.T) {
+	loadKeys(t, nil)
+	token, err := signClaims(jwt.SigningMethodHS256, "hs-0000000000000000", []byte("test-secret-key"))
+	require.NoError(t, err)
+
+	_, err = helper.ParseJWT(token)
+	assert.ErrorContains(t, err, "unknown signing key")
+}
+
+func TestJWTRejectsAlgorithmConfusion(t *testing.T) {
+	file := writePEM(t, newRSAKey(t), false)
+	loadKeys(t, map[string]string{"JWT_SIGNING_KEY_FILE": file})
+	rsaKeyID := helper.JWTKeys.Current.ID
+
+	// An HS256 token naming the RSA key, signed with its public key as the HMAC secret
+	publicPEM, err := os.ReadFile(writePEM(t, readPrivateKey(t, file), true))
+	require.NoError(t, err)
+	token, err := signClaims(jwt.SigningMethodHS256, rsaKeyID, publicPEM)
+	require.NoError(t, err)
+
+	_, err = helper.ParseJWT(token)
+	assert.ErrorContains(t, err, "unexpected signing method")
+}
+
+func TestJWTAcceptsLegacyTokens(t *testing.T) {
+	loadKeys(t, map[string]string{"JWT_SIGNING_KEY_FILE": writePEM(t, newRSAKey(t), false), "JWT_PREVIOUS_SECRETS": "test-secret-key"})
+
+	// legacyToken signs a token as issued before the key set, with only user_id and exp claims
+	legacyToken := func(method jwt.SigningMethod, key interface{}, expiresIn time.Duration) string {
+		token, err := jwt.NewWithClaims(method, jwt.MapClaims{
+			"user_id": 42,
+			"exp":     time.Now().Add(expiresIn).Unix(),
+		}).SignedString(key)
+		require.NoError(t, err)
+		return token
+	}
+
+	claims, err := helper.ParseJWT(legacyToken(jwt.SigningMethodHS256, []byte("test-secret-key"), 23*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), claims.UserID)
+
+	_, err = helper.ParseJWT(legacyToken(jwt.SigningMethodHS256, []byte("test-secret-key"), -time.Minute))
+	assert.ErrorContains(t, err, "token is expired")
+	_, err = helper.ParseJWT(legacyToken(jwt.SigningMethodHS256, []byte("test-secret-key"), 25*time.Hour))
+	assert.ErrorContains(t, err, "expires too late")
+	_, err = helper.ParseJWT(legacyToken(jwt.SigningMethodHS256, []byte("another-secret-key"), time.Hour))
+	assert.ErrorContains(t, err, "signature is invalid")
+	_, err = helper.ParseJWT(legacyToken(jwt.SigningMethodHS512, []byte("test-secret-key"), time.Hour))
+	assert.ErrorContains(t, err, "signing method HS512 is invalid")
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	loadKeys(t, map[string]string{
+		"JWT_SIGNING_KEY_FILE":   writePEM(t, rsaKey, false),
+		"JWT_PREVIOUS_KEY_FILES": writePEM(t, edKey, true),
+		"JWT_PREVIOUS_SECRETS":   "former-secret",
+	})
+
+	keys := helper.JWTKeys.JWKS()["keys"].([]map[string]string)
+	require.Len(t, keys, 2, "secrets are never published")
+	assert.Equal(t, "RSA", keys[0]["kty"])
+	assert.Equal(t, "RS256", keys[0]["alg"])
+	assert.Equal(t, helper.JWTKeys.Current.ID, keys[0]["kid"])
+	assert.Equal(t, "sig", keys[0]["use"])
+	assert.NotEmpty(t, keys[0]["n"])
+	assert.Equal(t, "AQAB", keys[0]["e"])
+	assert.NotContains(t, keys[0], "d", "private members are never published")
+
+	assert.Equal(t, "OKP", keys[1]["kty"])
+	assert.Equal(t, "Ed25519", keys[1]["crv"])
+	assert.Equal(t, "EdDSA", keys[1]["alg"])
+	_, ok := helper.JWTKeys.Lookup(keys[1]["kid"])
+	assert.True(t, ok)
+}
+
+// readPrivateKey parses the PKCS #8 key in a file written by writePEM
+func readPrivateKey(t *testing.T, file string) interface{} {
+	data, err := os.ReadFile(filepath.Clean(file))
+	require.NoError(t, err)
+	block, _ := pem.Decode(data)
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	require.NoError(t, err)
+	return key
+}
@@ -0,0 +1,21 @@
+package helper
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/logging"
+)
+
+// Logger returns the default structured logger annotated with the request ID and, once
+// authenticated, the user ID of the request and the API key it was made with
+func Logger(c *gin.Context) *slog.Logger {
+	logger := slog.Default().With(logging.RequestIDKey, c.GetString(logging.RequestIDKey))
+	if userID, exists := c.Get("user_id"); exists {
+		logger = logger.With("user_id", userID)
+	}
+	if apiKeyID, exists := c.Get("api_key_id"); exists {
+		logger = logger.With("api_key_id", apiKeyID)
+	}
+	return logger
+}
@@ -0,0 +1,25 @@
+package helper
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to target, both decoded JSON values, and returns
+// the result. Members of the patch set to null are removed from the target; a patch that is not an
+// object replaces the target entirely. target is not modified.
+func MergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, _ := target.(map[string]interface{})
+
+	result := make(map[string]interface{}, len(targetObject)+len(patchObject))
+	for name, value := range targetObject {
+		result[name] = value
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(result, name)
+			continue
+		}
+		result[name] = MergePatch(result[name], value)
+	}
+	return result
+}
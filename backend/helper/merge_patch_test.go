@@ -0,0 +1,46 @@
+package helper_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/helper"
+)
+
+func TestMergePatch(t *testing.T) {
+	// The examples of RFC 7396, appendix A, and a few of our own
+	tests := []struct {
+		name, target, patch, result string
+	}{
+		{"replace a member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add a member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null deletes a member", `{"a":"b"}`, `{"a":null}`, `{}`},
+		{"null deletes only that member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"null for a missing member", `{"a":"b"}`, `{"c":null}`, `{"a":"b"}`},
+		{"arrays are replaced", `{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"arrays replace values", `{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{"nested objects are merged", `{"a":{"b":"c","d":"e"}}`, `{"a":{"b":"x","d":null,"f":"g"}}`, `{"a":{"b":"x","f":"g"}}`},
+		{"objects replace non-objects", `{"a":"b"}`, `{"a":{"c":"d"}}`, `{"a":{"c":"d"}}`},
+		{"nested nulls are removed", `{"e":null}`, `{"a":{"bb":{"ccc":null}}}`, `{"e":null,"a":{"bb":{}}}`},
+		{"arrays are not merged", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"an array patch replaces the target", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"a string patch replaces the target", `{"a":"foo"}`, `"bar"`, `"bar"`},
+		{"a null patch replaces the target", `{"a":"foo"}`, `null`, `null`},
+		{"an object patch applies to a non-object target", `["a","b"]`, `{"a":"b"}`, `{"a":"b"}`},
+		{"an empty patch changes nothing", `{"a":"b"}`, `{}`, `{"a":"b"}`},
+	}
+	decode := func(data string) interface{} {
+		var value interface{}
+		require.NoError(t, json.Unmarshal([]byte(data), &value))
+		return value
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := decode(tt.target)
+			assert.Equal(t, decode(tt.result), helper.MergePatch(target, decode(tt.patch)))
+			assert.Equal(t, decode(tt.target), target, "the target is not modified")
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/user/tower-tracker-bima/backend/config"
+)
+
+//go:embed common_passwords.txt
+var commonPasswords []byte
+
+// maxPasswordBytes is the longest password bcrypt can hash
+const maxPasswordBytes = 72
+
+// passwordClasses describes the character classes a policy can require
+var passwordClasses = map[string]struct {
+	matches func(r rune) bool
+	message string
+}{
+	"lower":  {unicode.IsLower, "Must have a lowercase letter."},
+	"upper":  {unicode.IsUpper, "Must have an uppercase letter."},
+	"digit":  {unicode.IsDigit, "Must have a number."},
+	"symbol": {func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) }, "Must have a symbol."},
+}
+
+// PasswordPolicy decides which passwords users may choose
+type PasswordPolicy struct {
+	MinLength int
+	Classes   []string            // Keys of passwordClasses every password must contain
+	blocklist map[string]struct{} // Lowercase common and breached passwords
+}
+
+// Passwords is the policy applied whenever a password is set, set up by InitPasswordPolicy
+var Passwords *PasswordPolicy
+
+// InitPasswordPolicy sets up Passwords from the auth section of config.Current
+func InitPasswordPolicy() error {
+	policy, err := NewPasswordPolicy(config.Current.Auth)
+	if err != nil {
+		return err
+	}
+	Passwords = policy
+	return nil
+}
+
+// NewPasswordPolicy builds a policy from cfg, blocking the built-in list of common passwords
+// and those listed in cfg.PasswordBlocklistFile
+func NewPasswordPolicy(cfg config.AuthConfig) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{MinLength: cfg.PasswordMinLength, blocklist: make(map[string]struct{})}
+	for _, class := range cfg.PasswordClasses {
+		if class != "none" {
+			policy.Classes = append(policy.Classes, class)
+		}
+	}
+
+	if err := policy.addToBlocklist(bytes.NewReader(commonPasswords)); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordBlocklistFile != "" {
+		file, err := os.Open(cfg.PasswordBlocklistFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open password blocklist: %w", err)
+		}
+		defer file.Close()
+		if err := policy.addToBlocklist(file); err != nil {
+			return nil, fmt.Errorf("failed to read password blocklist %s: %w", cfg.PasswordBlocklistFile, err)
+		}
+	}
+	return policy, nil
+}
+
+// addToBlocklist reads one password per line, skipping empty lines and # comments
+func (p *PasswordPolicy) addToBlocklist(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Validate checks a new password of the account with the given email. The error message is meant
+// to be shown to the user.
+func (p *PasswordPolicy) Validate(password, email string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("Password must be at least %d characters.", p.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("Password must be at most %d bytes.", maxPasswordBytes)
+	}
+	for _, class := range p.Classes {
+		if !strings.ContainsFunc(password, passwordClasses[class].matches) {
+			return errors.New(passwordClasses[class].message)
+		}
+	}
+
+	lower := strings.ToLower(password)
+	if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+		localPart, _, _ := strings.Cut(email, "@")
+		if strings.Contains(lower, email) || (len(localPart) >= 3 && strings.Contains(lower, localPart)) {
+			return errors.New("Password must not contain your email address.")
+		}
+	}
+
+	// Also catch common passwords padded with digits or symbols, e.g. "Sunshine2024!"
+	core := strings.TrimFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) })
+	if p.isBlocked(lower) || p.isBlocked(core) {
+		return errors.New("Password is too common, please choose another one.")
+	}
+	return nil
+}
+
+func (p *PasswordPolicy) isBlocked(password string) bool {
+	if password == "" {
+		return false
+	}
+	_, blocked := p.blocklist[password]
+	return blocked
+}
+
+// ValidatePassword checks a new password of the account with the given email against Passwords
+func ValidatePassword(password, email string) error {
+	return Passwords.Validate(password, email)
+}
@@ -0,0 +1,60 @@
+package helper_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/helper"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(blocklist, []byte("# Leaked from the old intranet\nBimaTower\n\n"), 0o600))
+	policy, err := helper.NewPasswordPolicy(config.AuthConfig{
+		PasswordMinLength:     10,
+		PasswordClasses:       []string{"lower", "upper", "digit"},
+		PasswordBlocklistFile: blocklist,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name, password, email, error string
+	}{
+		{"valid", "Kelurahan-Paruga7", "jane.doe@example.com", ""},
+		{"too short", "Short7a", "", "Password must be at least 10 characters."},
+		{"length counts characters, not bytes", "Ñandú-Ölç9", "", ""},
+		{"longer than bcrypt allows", "Aa1" + strings.Repeat("x", 70), "", "Password must be at most 72 bytes."},
+		{"missing lowercase", "KELURAHAN-7", "", "Must have a lowercase letter."},
+		{"missing uppercase", "kelurahan-7", "", "Must have an uppercase letter."},
+		{"missing digit", "Kelurahan-Paruga", "", "Must have a number."},
+		{"built-in blocklist", "Password123", "", "Password is too common, please choose another one."},
+		{"blocklist padded with digits and symbols", "Sunshine2024!", "", "Password is too common, please choose another one."},
+		{"blocklist file, case-insensitive", "bimaTOWER-99", "", "Password is too common, please choose another one."},
+		{"blocklist comments are ignored", "Leaked from the old intranet 1", "", ""},
+		{"email local part", "Jane.Doe-2024x", "jane.doe@example.com", "Password must not contain your email address."},
+		{"whole email", "X1jane.doe@example.comX", " Jane.Doe@Example.com ", "Password must not contain your email address."},
+		{"short local parts are allowed", "Kelurahan-Jo7", "jo@example.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, tt.email)
+			if tt.error == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.error)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyWithoutClasses(t *testing.T) {
+	policy, err := helper.NewPasswordPolicy(config.AuthConfig{PasswordMinLength: 8, PasswordClasses: []string{"none"}})
+	require.NoError(t, err)
+	assert.NoError(t, policy.Validate("kelurahan paruga", ""))
+	assert.EqualError(t, policy.Validate("qwerty!!", ""), "Password is too common, please choose another one.")
+}
@@ -1,12 +1,16 @@
 package helper
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/logging"
+)
 
 // Response is a standardized JSON response structure
 type Response struct {
-	Status  string      `json:"status"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
+	Status    string      `json:"status"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data"`
+	RequestID string      `json:"request_id,omitempty"` // Set on errors, to be quoted in bug reports
 }
 
 // SendSuccessResponse sends a standardized success response.
@@ -20,10 +24,17 @@ func SendSuccessResponse(c *gin.Context, statusCode int, message string, data in
 
 // SendErrorResponse sends a standardized error response.
 func SendErrorResponse(c *gin.Context, statusCode int, message string) {
+	SendErrorResponseWithData(c, statusCode, message, nil)
+}
+
+// SendErrorResponseWithData sends a standardized error response carrying data, such as the
+// current state of a resource a write conflicted with.
+func SendErrorResponseWithData(c *gin.Context, statusCode int, message string, data interface{}) {
 	c.JSON(statusCode, Response{
-		Status:  "error",
-		Message: message,
-		Data:    nil,
+		Status:    "error",
+		Message:   message,
+		Data:      data,
+		RequestID: c.GetString(logging.RequestIDKey),
 	})
 	c.Abort()
 }
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/user/tower-tracker-bima/backend/backup"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/storage"
+	"gorm.io/gorm"
+)
+
+// StartBackupScheduler writes a backup archive to dir every interval until ctx is cancelled,
+// keeping only the newest retention archives.
+func StartBackupScheduler(ctx context.Context, db *gorm.DB, store storage.Storage, interval time.Duration, dir string, retention int) {
+	startPeriodic(ctx, interval, func(ctx context.Context) {
+		path, manifest, err := backup.WriteToDir(ctx, db, store, dir)
+		if err != nil {
+			log.Printf("Scheduled backup failed: %v", err)
+			return
+		}
+		log.Printf("Scheduled backup written to %s (%d files).", path, len(manifest.Files))
+
+		removed, err := backup.Prune(dir, retention)
+		if err != nil {
+			log.Printf("Failed to prune old backups: %v", err)
+		}
+		for _, name := range removed {
+			log.Printf("Removed old backup %s.", name)
+		}
+	})
+}
+
+// StartBackupSchedulerFromConfig starts scheduled backups when BACKUP_INTERVAL is set (e.g. "24h").
+// BACKUP_DIR and BACKUP_RETENTION default to "backups" and 7 archives.
+func StartBackupSchedulerFromConfig(ctx context.Context, db *gorm.DB, store storage.Storage, cfg config.BackupConfig) {
+	if cfg.Interval == 0 {
+		return
+	}
+
+	StartBackupScheduler(ctx, db, store, cfg.Interval, cfg.Dir, cfg.Retention)
+	log.Printf("Backups scheduled every %s to %s (keeping %d).", cfg.Interval, cfg.Dir, cfg.Retention)
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// running counts the background job goroutines, so that shutdown can let a run in progress finish
+var running struct {
+	sync.Mutex
+	count int
+	idle  chan struct{} // Closed when count drops to zero
+}
+
+// runs is the context job runs execute in. Cancelling the context a job was started with only stops
+// it from starting new runs: a run in progress goes on until Wait gives up on it.
+var runs, abortRuns = context.WithCancel(context.Background())
+
+// startPeriodic calls run every interval until ctx is cancelled
+func startPeriodic(ctx context.Context, interval time.Duration, run func(ctx context.Context)) {
+	running.Lock()
+	if running.count == 0 {
+		running.idle = make(chan struct{})
+	}
+	running.count++
+	running.Unlock()
+
+	go func() {
+		defer func() {
+			running.Lock()
+			running.count--
+			if running.count == 0 {
+				close(running.idle)
+			}
+			running.Unlock()
+		}()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if ctx.Err() != nil {
+					return // select picks at random when the tick and the cancellation are both pending
+				}
+				run(runs)
+			}
+		}
+	}()
+}
+
+// Wait blocks until every background job has stopped after its context was cancelled, or until ctx
+// is done, in which case the runs still in progress are cancelled
+func Wait(ctx context.Context) error {
+	running.Lock()
+	if running.count == 0 {
+		running.Unlock()
+		return nil
+	}
+	idle := running.idle
+	running.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		abortRuns()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoppedJobFinishesRunInProgress(t *testing.T) {
+	t.Cleanup(func() { runs, abortRuns = context.WithCancel(context.Background()) })
+
+	started := make(chan context.Context)
+	finish := make(chan struct{})
+	ctx, stop := context.WithCancel(context.Background())
+	startPeriodic(ctx, time.Millisecond, func(ctx context.Context) {
+		started <- ctx
+		select {
+		case <-finish:
+		case <-ctx.Done():
+		}
+	})
+
+	runCtx := <-started
+	stop()
+	waitCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, Wait(waitCtx), context.DeadlineExceeded, "the run in progress is not interrupted by stopping the job")
+
+	// Once Wait gives up, the run is cancelled and the job stops without starting another
+	select {
+	case <-runCtx.Done():
+	default:
+		t.Fatal("the run was not cancelled when Wait gave up")
+	}
+	require.NoError(t, Wait(context.Background()))
+}
+
+func TestStoppedJobIsWaitedFor(t *testing.T) {
+	finished := false
+	started := make(chan struct{})
+	ctx, stop := context.WithCancel(context.Background())
+	startPeriodic(ctx, time.Millisecond, func(ctx context.Context) {
+		close(started)
+		stop()
+		time.Sleep(10 * time.Millisecond)
+		assert.NoError(t, ctx.Err(), "runs keep going after the job is stopped")
+		finished = true
+	})
+
+	<-started
+	require.NoError(t, Wait(context.Background()))
+	assert.True(t, finished)
+}
@@ -0,0 +1,187 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/storage"
+	"gorm.io/gorm"
+)
+
+// Actions the upload reconciler can take on orphaned files
+const (
+	ActionReport     = "report"     // Only report orphans
+	ActionQuarantine = "quarantine" // Move orphans under storage.QuarantinePrefix, to be purged after the retention period
+	ActionDelete     = "delete"     // Delete orphans
+)
+
+// DefaultReconcileGracePeriod protects files of uploads whose database record is still being written
+const DefaultReconcileGracePeriod = 24 * time.Hour
+
+// DefaultQuarantineRetention is how long quarantined files are kept before they are purged
+const DefaultQuarantineRetention = 30 * 24 * time.Hour
+
+// ReconcileOptions controls a reconciliation run
+type ReconcileOptions struct {
+	Action      string        // One of ActionReport, ActionQuarantine or ActionDelete
+	GracePeriod time.Duration // Files younger than this are never treated as orphans
+
+	// QuarantineRetention is how long files stay in quarantine. Unless the action is ActionReport,
+	// quarantined files older than this are deleted; zero keeps them forever.
+	QuarantineRetention time.Duration
+}
+
+// MissingPhoto is a photo URL referenced from the database whose file is gone from storage
+type MissingPhoto struct {
+	TowerID uint   `json:"tower_id"`
+	PhotoID uint   `json:"photo_id,omitempty"` // Zero for the legacy Tower.PhotoURL
+	URL     string `json:"url"`
+}
+
+// ReconcileReport is the outcome of a reconciliation run
+type ReconcileReport struct {
+	Action        string               `json:"action"`
+	OrphanedFiles []storage.ObjectInfo `json:"orphaned_files"`
+	MissingPhotos []MissingPhoto       `json:"missing_photos"`
+	SkippedRecent int                  `json:"skipped_recent"` // Unreferenced files still within the grace period
+	Processed     int                  `json:"processed"`      // Orphans quarantined or deleted
+	Purged        int                  `json:"purged"`         // Quarantined files deleted after the retention period
+}
+
+// ReconcileUploads compares the stored upload files with the photo URLs in the database. It reports
+// files no tower references and referenced photos whose file is missing, and quarantines or deletes
+// the orphans older than the grace period when asked to. Files quarantined longer than the retention
+// period are deleted by every run that is not ActionReport.
+func ReconcileUploads(ctx context.Context, db *gorm.DB, store storage.Storage, opts ReconcileOptions) (*ReconcileReport, error) {
+	switch opts.Action {
+	case ActionReport, ActionQuarantine, ActionDelete:
+	default:
+		return nil, fmt.Errorf("unknown reconcile action %q", opts.Action)
+	}
+
+	objects, err := store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored files: %w", err)
+	}
+	stored := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		stored[object.Key] = true
+	}
+
+	// Soft-deleted towers and photos still count as references, so their files are never treated as orphans
+	var towers []models.Tower
+	if err := db.Unscoped().Select("id", "photo_url", "deleted_at").Find(&towers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load towers: %w", err)
+	}
+	var photos []models.TowerPhoto
+	if err := db.Unscoped().Find(&photos).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tower photos: %w", err)
+	}
+	// Photos of change requests awaiting approval are not referenced by any tower yet
+	var changeRequests []models.ChangeRequest
+	if err := db.Where("status = ? AND photo IS NOT NULL", models.ChangeRequestPending).Find(&changeRequests).Error; err != nil {
+		return nil, fmt.Errorf("failed to load change requests: %w", err)
+	}
+
+	report := &ReconcileReport{Action: opts.Action, OrphanedFiles: []storage.ObjectInfo{}, MissingPhotos: []MissingPhoto{}}
+	referenced := make(map[string]bool)
+	reference := func(url string, active bool, missing MissingPhoto) {
+		key, ok := store.KeyFromURL(url)
+		if !ok {
+			return
+		}
+		referenced[key] = true
+		if active && !stored[key] {
+			report.MissingPhotos = append(report.MissingPhotos, missing)
+		}
+	}
+
+	for _, tower := range towers {
+		reference(tower.PhotoURL, !tower.DeletedAt.Valid, MissingPhoto{TowerID: tower.ID, URL: tower.PhotoURL})
+	}
+	for _, photo := range photos {
+		active := !photo.DeletedAt.Valid
+		for _, url := range []string{photo.URL, photo.MediumURL, photo.ThumbnailURL} {
+			reference(url, active, MissingPhoto{TowerID: photo.TowerID, PhotoID: photo.ID, URL: url})
+		}
+	}
+	for _, request := range changeRequests {
+		if request.Photo == nil {
+			continue
+		}
+		for _, url := range []string{request.Photo.URL, request.Photo.MediumURL, request.Photo.ThumbnailURL} {
+			reference(url, false, MissingPhoto{})
+		}
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-opts.GracePeriod)
+	for _, object := range objects {
+		if strings.HasPrefix(object.Key, storage.QuarantinePrefix) {
+			// Moving a file into quarantine rewrites it, so its age is the time spent in quarantine
+			if opts.Action == ActionReport || opts.QuarantineRetention == 0 || now.Sub(object.ModTime) < opts.QuarantineRetention {
+				continue
+			}
+			if err := store.Delete(ctx, object.Key); err != nil {
+				return report, fmt.Errorf("failed to purge %s: %w", object.Key, err)
+			}
+			report.Purged++
+			continue
+		}
+		if referenced[object.Key] {
+			continue
+		}
+		if object.ModTime.After(cutoff) {
+			report.SkippedRecent++
+			continue
+		}
+		report.OrphanedFiles = append(report.OrphanedFiles, object)
+
+		switch opts.Action {
+		case ActionQuarantine:
+			err = storage.Move(ctx, store, object.Key, storage.QuarantinePrefix+object.Key)
+		case ActionDelete:
+			err = store.Delete(ctx, object.Key)
+		default:
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to %s %s: %w", opts.Action, object.Key, err)
+		}
+		report.Processed++
+	}
+
+	return report, nil
+}
+
+// StartUploadReconciler runs ReconcileUploads every interval until ctx is cancelled, logging each report.
+func StartUploadReconciler(ctx context.Context, db *gorm.DB, store storage.Storage, interval time.Duration, opts ReconcileOptions) {
+	startPeriodic(ctx, interval, func(ctx context.Context) {
+		report, err := ReconcileUploads(ctx, db, store, opts)
+		if err != nil {
+			log.Printf("Upload reconciliation failed: %v", err)
+			return
+		}
+		log.Printf("Upload reconciliation: %d orphaned files (%d %s), %d missing photos, %d recent files skipped, %d quarantined files purged",
+			len(report.OrphanedFiles), report.Processed, opts.Action, len(report.MissingPhotos), report.SkippedRecent, report.Purged)
+	})
+}
+
+// StartUploadReconcilerFromConfig starts the periodic reconciliation when RECONCILE_INTERVAL is set
+// (e.g. "24h"). RECONCILE_ACTION, RECONCILE_GRACE_PERIOD and RECONCILE_QUARANTINE_RETENTION default
+// to "report", 24h and 720h (30 days).
+func StartUploadReconcilerFromConfig(ctx context.Context, db *gorm.DB, store storage.Storage, cfg config.ReconcileConfig) {
+	if cfg.Interval == 0 {
+		return
+	}
+
+	opts := ReconcileOptions{Action: cfg.Action, GracePeriod: cfg.GracePeriod, QuarantineRetention: cfg.QuarantineRetention}
+	StartUploadReconciler(ctx, db, store, cfg.Interval, opts)
+	log.Printf("Upload reconciliation scheduled every %s (action: %s, grace period: %s, quarantine retention: %s).",
+		cfg.Interval, opts.Action, opts.GracePeriod, opts.QuarantineRetention)
+}
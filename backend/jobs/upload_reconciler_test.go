@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/storage"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+func TestReconcileUploadsPurgesQuarantine(t *testing.T) {
+	db := testutil.Setup(t, nil)
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := storage.NewLocalStorage(dir, "/uploads", nil)
+	require.NoError(t, err)
+
+	// put stores a file last modified age ago
+	put := func(key string, age time.Duration) {
+		require.NoError(t, store.Put(ctx, key, strings.NewReader("photo"), 5, "image/webp"))
+		modTime := time.Now().Add(-age)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, filepath.FromSlash(key)), modTime, modTime))
+	}
+	exists := func(key string) bool {
+		ok, err := store.Exists(ctx, key)
+		require.NoError(t, err)
+		return ok
+	}
+
+	put("referenced.webp", 48*time.Hour)
+	require.NoError(t, db.Create(&models.Tower{PhotoURL: store.URL("referenced.webp")}).Error)
+	put("orphan.webp", 48*time.Hour)
+	put("recent.webp", time.Hour)
+	put(storage.QuarantinePrefix+"expired.webp", 31*24*time.Hour)
+	put(storage.QuarantinePrefix+"kept.webp", 29*24*time.Hour)
+
+	opts := ReconcileOptions{Action: ActionReport, GracePeriod: DefaultReconcileGracePeriod, QuarantineRetention: DefaultQuarantineRetention}
+	report, err := ReconcileUploads(ctx, db, store, opts)
+	require.NoError(t, err)
+	assert.Zero(t, report.Purged, "reports change nothing")
+	assert.True(t, exists(storage.QuarantinePrefix+"expired.webp"))
+
+	opts.Action = ActionQuarantine
+	report, err = ReconcileUploads(ctx, db, store, opts)
+	require.NoError(t, err)
+	require.Len(t, report.OrphanedFiles, 1)
+	assert.Equal(t, "orphan.webp", report.OrphanedFiles[0].Key)
+	assert.Equal(t, 1, report.SkippedRecent)
+	assert.Equal(t, 1, report.Processed)
+	assert.Equal(t, 1, report.Purged)
+
+	assert.True(t, exists("referenced.webp"))
+	assert.True(t, exists("recent.webp"))
+	assert.False(t, exists("orphan.webp"))
+	assert.True(t, exists(storage.QuarantinePrefix+"orphan.webp"), "the retention starts when a file is quarantined")
+	assert.True(t, exists(storage.QuarantinePrefix+"kept.webp"))
+	assert.False(t, exists(storage.QuarantinePrefix+"expired.webp"))
+
+	opts.QuarantineRetention = 0
+	put(storage.QuarantinePrefix+"expired.webp", 365*24*time.Hour)
+	report, err = ReconcileUploads(ctx, db, store, opts)
+	require.NoError(t, err)
+	assert.Zero(t, report.Purged, "a retention of zero keeps quarantined files")
+	assert.True(t, exists(storage.QuarantinePrefix+"expired.webp"))
+}
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/user/tower-tracker-bima/backend/config"
+)
+
+// RequestIDKey is the key the request ID is stored under in the Gin context and in log records
+const RequestIDKey = "request_id"
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a copy of ctx carrying a request ID, which is added to records logged with it
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// Setup makes slog's default logger write JSON (or text) records to standard output at the configured
+// level. Messages from the standard log package are routed through it as well.
+func Setup(cfg config.LogConfig) {
+	options := &slog.HandlerOptions{Level: ParseLevel(cfg.Level)}
+
+	var handler slog.Handler
+	if cfg.Format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, options)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, options)
+	}
+	slog.SetDefault(slog.New(contextHandler{Handler: handler}))
+}
+
+// ParseLevel maps "debug", "info", "warn" and "error" to slog levels, defaulting to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// contextHandler adds the request ID of the context a record was logged with, e.g. by GORM queries
+// run with WithContext, so that they can be correlated with the request. Loggers that already carry
+// the request ID as an attribute are left alone.
+type contextHandler struct {
+	slog.Handler
+	hasRequestID bool
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" && !h.hasRequestID {
+		record.AddAttrs(slog.String(RequestIDKey, requestID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hasRequestID := h.hasRequestID
+	for _, attr := range attrs {
+		hasRequestID = hasRequestID || attr.Key == RequestIDKey
+	}
+	return contextHandler{h.Handler.WithAttrs(attrs), hasRequestID}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name), h.hasRequestID}
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// backgroundTimeout bounds a mail sent in the background, including its preparation
+const backgroundTimeout = 2 * time.Minute
+
+// pending tracks mails being sent in the background, so that shutdown can let them finish
+var pending sync.WaitGroup
+
+// Go runs send in the background with a context of its own, detached from the request that caused
+// the mail. Responses then take the same time whether or not a mail is sent, so they do not reveal
+// which addresses belong to accounts.
+func Go(send func(ctx context.Context)) {
+	pending.Add(1)
+	go func() {
+		defer pending.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), backgroundTimeout)
+		defer cancel()
+		send(ctx)
+	}()
+}
+
+// Wait blocks until every mail started with Go has been handed off or failed, or until ctx is done
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
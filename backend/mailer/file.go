@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes every email to a .eml file in a directory instead of sending it, for
+// development and tests
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+// NewFileMailer returns a mailer that writes messages from the given address into dir
+func NewFileMailer(from, dir string) *FileMailer {
+	return &FileMailer{from: from, dir: dir}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	path := filepath.Join(m.dir, fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000Z"), hex.EncodeToString(suffix)))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Mail written to file", "to", msg.To, "subject", msg.Subject, "path", path)
+	return nil
+}
+
+// LogMailer logs every email, including its body, instead of sending it, so that development
+// setups work without a mail server. Do not use it in production, as the logs then contain
+// password reset links.
+type LogMailer struct {
+	from string
+}
+
+// NewLogMailer returns a mailer that logs messages from the given address
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if _, err := format(m.from, msg, time.Now()); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Mail not sent, MAIL_DRIVER is log", "from", m.from, "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// NoopMailer drops every email, logging only its recipient and subject. It is the default, so that
+// nothing is sent, or leaked to the logs, until MAIL_DRIVER is set.
+type NoopMailer struct{}
+
+func (NoopMailer) Send(ctx context.Context, msg Message) error {
+	slog.WarnContext(ctx, "Mail suppressed, MAIL_DRIVER is not set", "to", msg.To, "subject", msg.Subject)
+	return nil
+}
@@ -0,0 +1,32 @@
+package mailer_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/mailer"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+func TestMailsSuppressedWithoutDriver(t *testing.T) {
+	testutil.LoadConfig(t, map[string]string{"MAIL_DRIVER": ""})
+	previous := mailer.Default
+	t.Cleanup(func() { mailer.Default = previous })
+	mailer.InitMailer()
+	require.IsType(t, mailer.NoopMailer{}, mailer.Default)
+
+	var logs bytes.Buffer
+	previousLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previousLogger) })
+
+	msg := mailer.Message{To: "jane@example.com", Subject: "Reset your password", Body: "http://app.test/reset-password?token=secret"}
+	require.NoError(t, mailer.Default.Send(context.Background(), msg))
+	assert.Contains(t, logs.String(), "Mail suppressed")
+	assert.Contains(t, logs.String(), "jane@example.com")
+	assert.NotContains(t, logs.String(), "secret", "bodies with reset links are never logged")
+}
@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/user/tower-tracker-bima/backend/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	// Send delivers msg, returning once the message has been handed off
+	Send(ctx context.Context, msg Message) error
+}
+
+// Default is the mailer used for outgoing emails, set up by InitMailer
+var Default Mailer
+
+// InitMailer sets up Default from the mail section of config.Current
+func InitMailer() {
+	cfg := config.Current.Mail
+	switch cfg.Driver {
+	case "smtp":
+		Default = NewSMTPMailer(cfg.From, cfg.SMTP)
+	case "file":
+		Default = NewFileMailer(cfg.From, cfg.Dir)
+	case "log":
+		Default = NewLogMailer(cfg.From)
+	default:
+		Default = NoopMailer{}
+	}
+	log.Printf("Mailer initialized using the %s driver.", cfg.Driver)
+}
+
+// format renders msg as an RFC 5322 message from the given sender
+func format(from string, msg Message, now time.Time) ([]byte, error) {
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes(), nil
+}
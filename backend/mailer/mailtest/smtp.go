@@ -0,0 +1,149 @@
+// Package mailtest provides an SMTP sink for tests: a minimal SMTP server that accepts every mail
+// and keeps it for inspection.
+package mailtest
+
+import (
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Mail is a message received by the sink
+type Mail struct {
+	From string
+	To   []string
+	Data string // Headers and body, with CRLF line endings
+}
+
+// SMTPSink is an SMTP server on a local port that accepts all mail
+type SMTPSink struct {
+	listener net.Listener
+
+	mu    sync.Mutex
+	mails []Mail
+	wg    sync.WaitGroup
+}
+
+// NewSMTPSink starts a sink that is closed when the test ends
+func NewSMTPSink(t testing.TB) *SMTPSink {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &SMTPSink{listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Host returns the host the sink listens on
+func (s *SMTPSink) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port returns the port the sink listens on
+func (s *SMTPSink) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Env returns the configuration variables that send mail to the sink
+func (s *SMTPSink) Env() map[string]string {
+	return map[string]string{
+		"MAIL_DRIVER": "smtp",
+		"SMTP_HOST":   s.Host(),
+		"SMTP_PORT":   strconv.Itoa(s.Port()),
+	}
+}
+
+// Mails returns the mails received so far
+func (s *SMTPSink) Mails() []Mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Mail(nil), s.mails...)
+}
+
+// Close stops the sink and waits for open connections to finish
+func (s *SMTPSink) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *SMTPSink) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle speaks just enough SMTP for net/smtp.SendMail, without STARTTLS or authentication
+func (s *SMTPSink) handle(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	reply := func(line string) bool {
+		return text.PrintfLine("%s", line) == nil
+	}
+
+	var mail Mail
+	if !reply("220 localhost SMTP sink") {
+		return
+	}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			mail = Mail{From: addressOf(arg)}
+			reply("250 OK")
+		case "RCPT":
+			mail.To = append(mail.To, addressOf(arg))
+			reply("250 OK")
+		case "DATA":
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.Data = strings.ReplaceAll(string(data), "\n", "\r\n")
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// addressOf extracts the address of a "FROM:<a@b>" or "TO:<a@b>" argument
+func addressOf(arg string) string {
+	start, end := strings.Index(arg, "<"), strings.LastIndex(arg, ">")
+	if start < 0 || end < start {
+		return arg
+	}
+	return arg[start+1 : end]
+}
@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/user/tower-tracker-bima/backend/config"
+)
+
+// SMTPMailer delivers emails through an SMTP server, upgrading the connection with STARTTLS when
+// the server offers it
+type SMTPMailer struct {
+	from string
+	cfg  config.SMTPConfig
+}
+
+// NewSMTPMailer returns a mailer that sends from the given address through the configured server
+func NewSMTPMailer(from string, cfg config.SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{from: from, cfg: cfg}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := format(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", m.from, err)
+	}
+	recipient, _ := mail.ParseAddress(msg.To) // Validated by format
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	// smtp.SendMail cannot be cancelled, so it runs in the background and is abandoned when ctx is done
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, sender.Address, []string{recipient.Address}, data)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send mail through %s: %w", addr, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,57 @@
+package mailer_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/mailer"
+	"github.com/user/tower-tracker-bima/backend/mailer/mailtest"
+)
+
+func TestSMTPMailerSend(t *testing.T) {
+	sink := mailtest.NewSMTPSink(t)
+	m := mailer.NewSMTPMailer("Tower Tracker <no-reply@example.com>", config.SMTPConfig{Host: sink.Host(), Port: sink.Port()})
+
+	err := m.Send(context.Background(), mailer.Message{
+		To:      "Jane Doe <jane@example.com>",
+		Subject: "Grüße",
+		Body:    "Line one\nLine two\n",
+	})
+	require.NoError(t, err)
+
+	mails := sink.Mails()
+	require.Len(t, mails, 1)
+	assert.Equal(t, "no-reply@example.com", mails[0].From)
+	assert.Equal(t, []string{"jane@example.com"}, mails[0].To)
+	headers, body, found := strings.Cut(mails[0].Data, "\r\n\r\n")
+	require.True(t, found)
+	assert.Contains(t, headers, "From: Tower Tracker <no-reply@example.com>\r\n")
+	assert.Contains(t, headers, "To: Jane Doe <jane@example.com>\r\n")
+	assert.Contains(t, headers, "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n")
+	assert.Equal(t, "Line one\r\nLine two\r\n", body)
+}
+
+func TestSMTPMailerRejectsInvalidRecipient(t *testing.T) {
+	sink := mailtest.NewSMTPSink(t)
+	m := mailer.NewSMTPMailer("no-reply@example.com", config.SMTPConfig{Host: sink.Host(), Port: sink.Port()})
+
+	err := m.Send(context.Background(), mailer.Message{To: "not an address", Subject: "Hello"})
+	assert.ErrorContains(t, err, "invalid recipient")
+	assert.Empty(t, sink.Mails())
+}
+
+func TestGoUsesOwnContext(t *testing.T) {
+	var sendErr error
+	var hasDeadline bool
+	mailer.Go(func(ctx context.Context) {
+		sendErr = ctx.Err()
+		_, hasDeadline = ctx.Deadline()
+	})
+	require.NoError(t, mailer.Wait(context.Background()))
+	assert.NoError(t, sendErr)
+	assert.True(t, hasDeadline, "mails sent in the background are bounded in time")
+}
@@ -1,43 +1,140 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
 
 	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/contrib/static"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/user/tower-tracker-bima/backend/cli"
+	"github.com/user/tower-tracker-bima/backend/config"
 	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/jobs"
+	"github.com/user/tower-tracker-bima/backend/logging"
+	"github.com/user/tower-tracker-bima/backend/mailer"
+	"github.com/user/tower-tracker-bima/backend/metrics"
+	"github.com/user/tower-tracker-bima/backend/middleware"
+	"github.com/user/tower-tracker-bima/backend/oidc"
+	"github.com/user/tower-tracker-bima/backend/ratelimit"
 	"github.com/user/tower-tracker-bima/backend/routes"
-	"github.com/gin-gonic/contrib/static"
+	"github.com/user/tower-tracker-bima/backend/storage"
 )
 
+// commands maps each subcommand to its implementation. Without a subcommand the server is started.
+var commands = map[string]func(args []string) error{
+	"serve":             serve,
+	"migrate":           cli.Migrate,
+	"create-admin":      cli.CreateAdmin,
+	"reset-password":    cli.ResetPassword,
+	"import-towers":     cli.ImportTowers,
+	"export":            cli.Export,
+	"backup":            cli.Backup,
+	"restore":           cli.Restore,
+	"reconcile-uploads": cli.ReconcileUploads,
+	"generate-jwt-key":  cli.GenerateJWTKey,
+}
+
 func main() {
-	// Load .env file
-	err := godotenv.Load()
+	// Load and validate the configuration shared by all commands
+	cfg, err := config.Load()
 	if err != nil {
-		log.Println("Error loading .env file, using environment variables directly")
+		log.Fatal(err)
+	}
+	logging.Setup(cfg.Log)
+
+	name, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		name, args = os.Args[1], os.Args[2:]
+	}
+
+	command, ok := commands[name]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for commandName := range commands {
+			names = append(names, commandName)
+		}
+		sort.Strings(names)
+		log.Fatalf("Unknown command %q, available commands: %s", name, strings.Join(names, ", "))
+	}
+	if err := command(args); err != nil {
+		log.Fatalf("%s failed: %v", name, err)
+	}
+}
+
+// serve starts the HTTP server
+func serve(args []string) error {
+	// Initialize Database and make sure its schema is current.
+	// Set AUTO_MIGRATE=true to apply pending migrations on startup instead of refusing to start.
+	cfg := config.Current
+	if err := cli.InitDatabase(cfg.Database.AutoMigrate); err != nil {
+		return err
+	}
+	cli.WarnIfNoUsers()
+
+	// Record database metrics and expose domain counts
+	if err := database.DB.Use(&metrics.GORMPlugin{}); err != nil {
+		return err
 	}
+	metrics.RegisterDomainCollector(database.DB)
 
-	// Initialize Database
-	database.ConnectDatabase()
-	database.SeedAdminUser()
+	// Initialize upload storage, the rate limiter, the mailer and single sign-on
+	storage.InitStorage()
+	ratelimit.InitLimiter()
+	mailer.InitMailer()
+	oidc.InitOIDC()
+	if err := helper.InitPasswordPolicy(); err != nil {
+		return err
+	}
+	if err := helper.InitJWTKeys(); err != nil {
+		return err
+	}
+
+	// The server shuts down when SIGINT or SIGTERM is received
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start background jobs. Stopping them only prevents new runs: a backup or reconciliation in
+	// progress gets until the shutdown timeout to finish.
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	jobs.StartUploadReconcilerFromConfig(jobsCtx, database.DB, storage.Default, cfg.Reconcile)
+	jobs.StartBackupSchedulerFromConfig(jobsCtx, database.DB, storage.Default, cfg.Backup)
 
 	// Initialize Gin Router
-	router := gin.Default()
+	router := gin.New()
 	router.RedirectTrailingSlash = true // Enable automatic redirect for trailing slashes
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.LoggerMiddleware())
+	router.Use(middleware.RecoveryMiddleware())
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(middleware.MaxBodySize(cfg.Server.MaxBodyBytes))
 
 	// CORS Middleware
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:8080", "https://1911533edb53.ngrok-free.app"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowOrigins:     cfg.CORS.AllowOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "ETag", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           86400,
 	}))
 
-	// Serve static files for uploaded photos
-	router.Static("/uploads", "./uploads")
+	// Health, readiness, build information and metrics
+	routes.HealthRoutes(router)
+	routes.MetricsRoutes(router)
+	routes.WellKnownRoutes(router)
+
+	// Serve uploaded photos from the configured storage backend
+	routes.UploadRoutes(router)
 
 	// Setup API routes
 	log.Println("Registering Auth Routes...")
@@ -48,6 +145,12 @@ func main() {
 	routes.TowerRoutes(router)
 	log.Println("Registering Blankspot Routes...")
 	routes.BlankspotRoutes(router)
+	log.Println("Registering Admin Routes...")
+	routes.AdminRoutes(router)
+	log.Println("Registering API Key Routes...")
+	routes.APIKeyRoutes(router)
+	log.Println("Registering Change Request Routes...")
+	routes.ChangeRequestRoutes(router)
 	log.Println("All API routes registered.")
 
 	// Serve static frontend files from the './frontend/dist' directory inside the container
@@ -58,8 +161,45 @@ func main() {
 	})
 
 	// Start server
-	log.Println("Starting server on port 8080...")
-	if err := router.Run(":8080"); err != nil {
-		log.Fatal("Failed to start server: ", err)
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Starting server on port %d...", cfg.Port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop() // A second signal terminates immediately
+	stopJobs()
+
+	// Drain in-flight requests, then wait for running jobs and mails and close the database
+	log.Printf("Shutting down, waiting up to %s for in-flight requests and jobs...", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to drain requests: %v", err)
+	}
+	if err := jobs.Wait(shutdownCtx); err != nil {
+		log.Printf("Background jobs did not stop in time: %v", err)
+	}
+	if err := mailer.Wait(shutdownCtx); err != nil {
+		log.Printf("Mails were still being sent at shutdown: %v", err)
+	}
+	if err := database.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
 	}
-}
\ No newline at end of file
+	log.Println("Server stopped.")
+	return nil
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// domainQueryTimeout bounds the queries run on every scrape
+const domainQueryTimeout = 5 * time.Second
+
+var (
+	towersDesc     = prometheus.NewDesc(namespace+"_towers", "Towers, by status.", []string{"status"}, nil)
+	providersDesc  = prometheus.NewDesc(namespace+"_providers", "Providers.", nil, nil)
+	blankspotsDesc = prometheus.NewDesc(namespace+"_blankspot_areas", "Blankspot areas.", nil, nil)
+)
+
+// DomainCollector reports counts of towers by status, providers and blankspot areas,
+// queried from the database on every scrape so that they are never stale.
+type DomainCollector struct {
+	DB *gorm.DB
+}
+
+// RegisterDomainCollector registers a DomainCollector for db with the default registry
+func RegisterDomainCollector(db *gorm.DB) {
+	prometheus.MustRegister(&DomainCollector{DB: db})
+}
+
+func (c *DomainCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- towersDesc
+	ch <- providersDesc
+	ch <- blankspotsDesc
+}
+
+func (c *DomainCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), domainQueryTimeout)
+	defer cancel()
+	db := c.DB.WithContext(ctx)
+
+	var towers []struct {
+		Status string
+		Count  int64
+	}
+	if err := db.Table("towers").Select("status, COUNT(*) AS count").Where("deleted_at IS NULL").Group("status").Scan(&towers).Error; err != nil {
+		log.Printf("Metrics: failed to count towers: %v", err)
+	}
+	for _, row := range towers {
+		ch <- prometheus.MustNewConstMetric(towersDesc, prometheus.GaugeValue, float64(row.Count), row.Status)
+	}
+
+	for desc, table := range map[*prometheus.Desc]string{providersDesc: "providers", blankspotsDesc: "blankspot_areas"} {
+		var count int64
+		if err := db.Table(table).Where("deleted_at IS NULL").Count(&count).Error; err != nil {
+			log.Printf("Metrics: failed to count %s: %v", table, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(count))
+	}
+}
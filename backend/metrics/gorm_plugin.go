@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// startTimeKey stores the start of a statement on the GORM instance
+const startTimeKey = "metrics:start_time"
+
+// GORMPlugin records the duration and failures of every database statement.
+// Register it with db.Use(&metrics.GORMPlugin{}).
+type GORMPlugin struct{}
+
+func (p *GORMPlugin) Name() string {
+	return "metrics"
+}
+
+func (p *GORMPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("*").Register("metrics:before_create", before),
+		callback.Create().After("*").Register("metrics:after_create", afterOperation("create")),
+		callback.Query().Before("*").Register("metrics:before_query", before),
+		callback.Query().After("*").Register("metrics:after_query", afterOperation("query")),
+		callback.Update().Before("*").Register("metrics:before_update", before),
+		callback.Update().After("*").Register("metrics:after_update", afterOperation("update")),
+		callback.Delete().Before("*").Register("metrics:before_delete", before),
+		callback.Delete().After("*").Register("metrics:after_delete", afterOperation("delete")),
+		callback.Row().Before("*").Register("metrics:before_row", before),
+		callback.Row().After("*").Register("metrics:after_row", afterOperation("row")),
+		callback.Raw().Before("*").Register("metrics:before_raw", before),
+		callback.Raw().After("*").Register("metrics:after_raw", afterOperation("raw")),
+	)
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(startTimeKey, time.Now())
+}
+
+// afterOperation returns the callback recording a finished statement of operation
+func afterOperation(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startTimeKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		observe(db, operation, start)
+	}
+}
+
+func observe(db *gorm.DB, operation string, start time.Time) {
+	table := db.Statement.Table
+	if table == "" {
+		table = "unknown"
+	}
+	DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		DBQueryErrors.WithLabelValues(operation, table).Inc()
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+)
+
+// Context keys holding the scopes set by AllowAPIKeys
+const (
+	apiKeyReadScopeKey  = "api_key_read_scope"
+	apiKeyWriteScopeKey = "api_key_write_scope"
+)
+
+// lastUsedResolution limits how often the last use of an API key is written to the database
+const lastUsedResolution = time.Minute
+
+// AllowAPIKeys lets API keys call the routes it is applied to. Safe requests (GET, HEAD) need
+// readScope, others writeScope; an empty scope refuses API keys for those requests. It must run
+// before AuthMiddleware: routes without it only accept user tokens. It is applied to the tower,
+// provider and blankspot routes; accounts, API keys, change requests and admin routes need a login.
+func AllowAPIKeys(readScope, writeScope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiKeyReadScopeKey, readScope)
+		c.Set(apiKeyWriteScopeKey, writeScope)
+		c.Next()
+	}
+}
+
+// authenticateAPIKey authenticates the request with the key from the X-API-Key header, checking
+// that the route accepts API keys and that the key holds the scope it requires
+func authenticateAPIKey(c *gin.Context, key string) {
+	prefix, ok := helper.APIKeyPrefixOf(key)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return
+	}
+
+	var apiKey models.APIKey
+	if err := database.DB.Where("prefix = ?", prefix).Limit(1).Find(&apiKey).Error; err != nil {
+		helper.Logger(c).Error("Failed to look up API key", "prefix", prefix, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API key"})
+		c.Abort()
+		return
+	}
+	if apiKey.ID == 0 || subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(helper.HashAPIKey(key))) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return
+	}
+	if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key has expired"})
+		c.Abort()
+		return
+	}
+
+	scopeKey := apiKeyWriteScopeKey
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		scopeKey = apiKeyReadScopeKey
+	}
+	scope := c.GetString(scopeKey)
+	if scope == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint cannot be used with an API key"})
+		c.Abort()
+		return
+	}
+	if !apiKey.HasScope(scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + scope + " scope"})
+		c.Abort()
+		return
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedResolution || apiKey.LastUsedIP != c.ClientIP() {
+		err := database.DB.Model(&apiKey).UpdateColumns(map[string]interface{}{"last_used_at": now, "last_used_ip": c.ClientIP()}).Error
+		if err != nil {
+			helper.Logger(c).Error("Failed to record API key use", "api_key_id", apiKey.ID, "error", err)
+		}
+	}
+
+	// Requests act as the owner of the key, with the same restrictions
+	c.Set("api_key_id", apiKey.ID)
+	if !setAuthenticatedUser(c, apiKey.UserID, nil) {
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+// createAPIKey stores a key of user with scopes, expiring at expiresAt unless nil, and returns it
+// with the secret
+func createAPIKey(t *testing.T, userID uint, scopes string, expiresAt *time.Time) (models.APIKey, string) {
+	key, prefix, hash, err := helper.GenerateAPIKey()
+	require.NoError(t, err)
+	apiKey := models.APIKey{UserID: userID, Name: "Integration", Prefix: prefix, KeyHash: hash, Scopes: scopes, ExpiresAt: expiresAt}
+	require.NoError(t, database.DB.Create(&apiKey).Error)
+	return apiKey, key
+}
+
+// newAPIKeyRouter serves /towers open to API keys like the tower routes, /staff open to API keys
+// of staff accounts only and /account closed to them, all echoing the authenticated user
+func newAPIKeyRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	echo := func(c *gin.Context) {
+		providerID, _ := c.Get("provider_id")
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id"), "api_key_id": c.GetUint("api_key_id"), "provider_id": providerID})
+	}
+
+	router := gin.New()
+	router.Any("/towers", AllowAPIKeys(models.ScopeRead, models.ScopeTowersWrite), AuthMiddleware(), echo)
+	router.Any("/staff", AllowAPIKeys(models.ScopeRead, models.ScopeProvidersWrite), AuthMiddleware(), RequireStaff(), echo)
+	router.GET("/account", AuthMiddleware(), echo)
+	return router
+}
+
+// withAPIKey sends a request with key in the X-API-Key header
+func withAPIKey(router *gin.Engine, method, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-API-Key", key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	testutil.Setup(t, nil)
+	router := newAPIKeyRouter()
+	user := models.User{Name: "Integration", Email: "integration@example.com", Password: "x"}
+	require.NoError(t, database.DB.Create(&user).Error)
+
+	apiKey, key := createAPIKey(t, user.ID, models.ScopeRead+","+models.ScopeTowersWrite, nil)
+	_, readOnlyKey := createAPIKey(t, user.ID, models.ScopeRead, nil)
+	expired := time.Now().Add(-time.Minute)
+	_, expiredKey := createAPIKey(t, user.ID, models.ScopeRead, &expired)
+	revoked, revokedKey := createAPIKey(t, user.ID, models.ScopeRead, nil)
+	require.NoError(t, database.DB.Delete(&revoked).Error)
+
+	// A key with the right prefix whose secret does not match the stored hash
+	otherKey, _, _, err := helper.GenerateAPIKey()
+	require.NoError(t, err)
+	prefix, _ := helper.APIKeyPrefixOf(key)
+	otherPrefix, _ := helper.APIKeyPrefixOf(otherKey)
+	wrongSecret := prefix + otherKey[len(otherPrefix):]
+	require.NotEqual(t, key, wrongSecret)
+
+	tests := []struct {
+		name, method, path, key string
+		status                  int
+		error                   string
+	}{
+		{"malformed", http.MethodGet, "/towers", "not-a-key", http.StatusUnauthorized, "Invalid API key"},
+		{"unknown prefix", http.MethodGet, "/towers", otherKey, http.StatusUnauthorized, "Invalid API key"},
+		{"hash mismatch", http.MethodGet, "/towers", wrongSecret, http.StatusUnauthorized, "Invalid API key"},
+		{"expired", http.MethodGet, "/towers", expiredKey, http.StatusUnauthorized, "API key has expired"},
+		{"revoked", http.MethodGet, "/towers", revokedKey, http.StatusUnauthorized, "Invalid API key"},
+		{"read-only key writing", http.MethodPost, "/towers", readOnlyKey, http.StatusForbidden, "API key lacks the towers:write scope"},
+		{"key without the route's scope", http.MethodPost, "/staff", key, http.StatusForbidden, "API key lacks the providers:write scope"},
+		{"route closed to keys", http.MethodGet, "/account", key, http.StatusForbidden, "This endpoint cannot be used with an API key"},
+		{"read", http.MethodGet, "/towers", readOnlyKey, http.StatusOK, ""},
+		{"write", http.MethodPost, "/towers", key, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := withAPIKey(router, tt.method, tt.path, tt.key)
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			var body map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if tt.error != "" {
+				assert.Equal(t, tt.error, body["error"])
+			} else {
+				assert.Equal(t, float64(user.ID), body["user_id"])
+				assert.Nil(t, body["provider_id"])
+			}
+		})
+	}
+
+	w := withAPIKey(router, http.MethodPost, "/towers", key)
+	require.Equal(t, http.StatusOK, w.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, float64(apiKey.ID), body["api_key_id"])
+	require.NoError(t, database.DB.First(&apiKey, apiKey.ID).Error)
+	require.NotNil(t, apiKey.LastUsedAt, "the last use is recorded")
+	assert.WithinDuration(t, time.Now(), *apiKey.LastUsedAt, time.Minute)
+	assert.Equal(t, "192.0.2.1", apiKey.LastUsedIP)
+}
+
+func TestAPIKeyOfOperatorAccount(t *testing.T) {
+	testutil.Setup(t, nil)
+	router := newAPIKeyRouter()
+	provider := models.Provider{Name: "Operator A"}
+	require.NoError(t, database.DB.Create(&provider).Error)
+	operator := models.User{Name: "Operator", Email: "operator@example.com", Password: "x", ProviderID: &provider.ID}
+	require.NoError(t, database.DB.Create(&operator).Error)
+	_, key := createAPIKey(t, operator.ID, models.ScopeRead+","+models.ScopeTowersWrite+","+models.ScopeProvidersWrite, nil)
+
+	// Requests act as the operator, limited to its provider by the handlers
+	w := withAPIKey(router, http.MethodPost, "/towers", key)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, float64(operator.ID), body["user_id"])
+	assert.Equal(t, float64(provider.ID), body["provider_id"])
+
+	// and refused on staff routes, whatever the scopes of the key
+	w = withAPIKey(router, http.MethodPost, "/staff", key)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "restricted to staff accounts")
+}
@@ -1,17 +1,24 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/user/tower-tracker-bima/backend/database"
 	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
 )
 
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Integrations authenticate with an API key instead of a user token
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			authenticateAPIKey(c, apiKey)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
@@ -28,35 +35,81 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := parts[1]
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return helper.JWT_SECRET, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := helper.ParseJWT(tokenString)
+		if err != nil {
+			helper.Logger(c).Debug("Rejected token", "error", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+		if !setAuthenticatedUser(c, claims.UserID, claims) {
 			return
 		}
+		c.Next()
+	}
+}
+
+// setAuthenticatedUser loads the user a request is made by and stores its ID and, for operator
+// accounts, its provider ID in the context. Requests of deleted users are refused, as are tokens,
+// passed as claims unless the request uses an API key, issued before the password was reset.
+func setAuthenticatedUser(c *gin.Context, userID uint, claims *helper.TokenClaims) bool {
+	var user models.User
+	if err := database.DB.Select("id", "provider_id", "password_changed_at").Limit(1).Find(&user, userID).Error; err != nil {
+		helper.Logger(c).Error("Failed to load authenticated user", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+		c.Abort()
+		return false
+	}
+	if user.ID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User no longer exists"})
+		c.Abort()
+		return false
+	}
+	// Issue times are rounded to the second, legacy tokens have none
+	if claims != nil && user.PasswordChangedAt != nil &&
+		(claims.IssuedAt == nil || claims.IssuedAt.Before(user.PasswordChangedAt.Truncate(time.Second))) {
+		helper.Logger(c).Debug("Rejected token issued before the password was changed", "target_user_id", user.ID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		c.Abort()
+		return false
+	}
+
+	c.Set("user_id", user.ID)
+	if user.ProviderID != nil {
+		c.Set("provider_id", *user.ProviderID)
+	}
+	return true
+}
 
-		// Safely get user_id, convert from float64 (standard for JSON numbers) to uint
-		userIDFloat, ok := claims["user_id"].(float64)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+// RequireStaff refuses operator accounts linked to a provider. It must run after AuthMiddleware.
+func RequireStaff() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isOperator := c.Get("provider_id"); isOperator {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This action is restricted to staff accounts"})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		c.Set("user_id", uint(userIDFloat))
+// RequireApprover restricts a route to accounts with approver rights. It must run after AuthMiddleware.
+func RequireApprover() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		if err := database.DB.Select("id", "is_approver").Limit(1).Find(&user, c.MustGet("user_id")).Error; err != nil {
+			helper.Logger(c).Error("Failed to load authenticated user", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+			c.Abort()
+			return
+		}
+		if !user.IsApprover {
+			helper.Logger(c).Warn("Refused approver-only request", "path", c.FullPath())
+			c.JSON(http.StatusForbidden, gin.H{"error": "This action requires approver rights"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
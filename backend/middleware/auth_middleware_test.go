@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+// tokenIssuedAt signs a token for user like GenerateJWT, but issued at the given time
+func tokenIssuedAt(t *testing.T, userID uint, issuedAt time.Time) string {
+	cfg := config.Current.Auth
+	key := helper.JWTKeys.Current
+	token := jwt.NewWithClaims(key.Method, helper.TokenClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    cfg.JWTIssuer,
+			Audience:  jwt.ClaimStrings{cfg.JWTAudience},
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			ID:        "token-1",
+		},
+	})
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.SignKey)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestAuthMiddlewareRefusesTokensIssuedBeforePasswordChange(t *testing.T) {
+	testutil.Setup(t, nil)
+	router := gin.New()
+	router.GET("/account", AuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/account", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	user := models.User{Name: "Jane", Email: "jane@example.com", Password: "x"}
+	require.NoError(t, database.DB.Create(&user).Error)
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("test-secret-key"))
+	require.NoError(t, err)
+	before := tokenIssuedAt(t, user.ID, time.Now().Add(-2*time.Hour))
+	assert.Equal(t, http.StatusOK, get(before))
+	assert.Equal(t, http.StatusOK, get(legacy))
+
+	changedAt := time.Now().Add(-time.Hour)
+	require.NoError(t, database.DB.Model(&user).Update("password_changed_at", changedAt).Error)
+	assert.Equal(t, http.StatusUnauthorized, get(before))
+	assert.Equal(t, http.StatusUnauthorized, get(legacy), "legacy tokens carry no issue time")
+	assert.Equal(t, http.StatusOK, get(tokenIssuedAt(t, user.ID, changedAt)), "tokens issued in the second of the change")
+	token, err := helper.GenerateJWT(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, get(token))
+}
@@ -1,33 +1,112 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
-	"sync"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/metrics"
+	"github.com/user/tower-tracker-bima/backend/ratelimit"
 )
 
-// IPClients stores a map of IP addresses to their respective rate limiters.
-var IPClients = make(map[string]*rate.Limiter)
-var mu sync.Mutex
+// RateLimitKeyFunc extracts what a policy limits from a request. Returning an empty string
+// skips the policy for that request.
+type RateLimitKeyFunc func(c *gin.Context) string
 
-// RateLimitMiddleware returns a Gin middleware that limits requests per IP address.
-func RateLimitMiddleware(limit float64, burst int) gin.HandlerFunc {
+// RateLimitPolicy limits requests sharing the same key
+type RateLimitPolicy struct {
+	Name  string // Namespaces the keys of the policy and labels its rejections, e.g. "login_email"
+	Limit ratelimit.Limit
+	Key   RateLimitKeyFunc
+}
+
+// KeyByIP limits each client IP address
+func KeyByIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// KeyByUser limits each authenticated user; it must run after AuthMiddleware.
+// Unauthenticated requests are limited by IP address instead.
+func KeyByUser(c *gin.Context) string {
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByEmail limits each account by the "email" field of a JSON request body, so that brute force
+// against one account is limited however many addresses it comes from. The body is left intact
+// for the handler.
+func KeyByEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var input struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &input) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(input.Email))
+}
+
+// RateLimitMiddleware returns a Gin middleware that checks every request against the given policies
+// using ratelimit.Default. The X-RateLimit-* headers describe the most restrictive policy; rejected
+// requests get 429 with Retry-After. If the limiter fails, requests are let through.
+func RateLimitMiddleware(policies ...RateLimitPolicy) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ip := c.ClientIP()
+		var tightest *ratelimit.Result
+		for _, policy := range policies {
+			key := policy.Key(c)
+			if key == "" {
+				continue
+			}
+
+			result, err := ratelimit.Default.Allow(c.Request.Context(), policy.Name+":"+key, policy.Limit)
+			if err != nil {
+				helper.Logger(c).Error("Rate limiter failed, allowing request", "policy", policy.Name, "error", err)
+				continue
+			}
 
-		mu.Lock()
-		if _, found := IPClients[ip]; !found {
-			IPClients[ip] = rate.NewLimiter(rate.Limit(limit), burst)
+			if !result.Allowed {
+				metrics.RateLimitRejections.WithLabelValues(c.FullPath(), policy.Name).Inc()
+				setRateLimitHeaders(c, result)
+				c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter.Seconds())))
+				helper.SendErrorResponse(c, http.StatusTooManyRequests, "Too many requests, please try again later")
+				return
+			}
+			if tightest == nil || result.Remaining < tightest.Remaining {
+				tightest = &result
+			}
 		}
-		limiter := IPClients[ip]
-		mu.Unlock()
 
-		if !limiter.Allow() {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
-			return
+		if tightest != nil {
+			setRateLimitHeaders(c, *tightest)
 		}
 		c.Next()
 	}
 }
+
+func setRateLimitHeaders(c *gin.Context, result ratelimit.Result) {
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter.Seconds())))
+}
+
+// ceilSeconds rounds up, so that clients never retry too early
+func ceilSeconds(seconds float64) int {
+	return int(math.Ceil(seconds))
+}
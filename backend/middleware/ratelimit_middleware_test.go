@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/ratelimit"
+)
+
+// newRateLimitedRouter serves POST /login limited like the login route, with a handler that
+// echoes the email of the JSON body
+func newRateLimitedRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	previous := ratelimit.Default
+	ratelimit.Default = ratelimit.NewMemoryLimiter(100)
+	t.Cleanup(func() { ratelimit.Default = previous })
+
+	router := gin.New()
+	router.POST("/login", RateLimitMiddleware(
+		RateLimitPolicy{Name: "login_ip", Limit: ratelimit.Limit{Count: 5, Period: time.Minute}, Key: KeyByIP},
+		RateLimitPolicy{Name: "login_email", Limit: ratelimit.Limit{Count: 2, Period: time.Minute}, Key: KeyByEmail},
+	), func(c *gin.Context) {
+		var input struct {
+			Email    string `json:"email" binding:"required"`
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, input.Email)
+	})
+	return router
+}
+
+func postLogin(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitMiddlewareHeaders(t *testing.T) {
+	router := newRateLimitedRouter(t)
+	body := `{"email": "Jane@Example.com", "password": "secret"}`
+
+	// The headers describe the most restrictive policy, here the one per email
+	w := postLogin(router, body)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("X-RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	w = postLogin(router, body)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	// Emails are compared case-insensitively
+	w = postLogin(router, `{"email": " jane@example.com", "password": "secret"}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("X-RateLimit-Reset"))
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	// Other emails from the same address are still limited by IP only
+	w = postLogin(router, `{"email": "john@example.com", "password": "secret"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestRateLimitMiddlewareSkipsPoliciesWithoutKey(t *testing.T) {
+	router := newRateLimitedRouter(t)
+
+	w := postLogin(router, `not json`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "5", w.Header().Get("X-RateLimit-Limit"), "only the IP policy applies")
+	assert.Equal(t, "4", w.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestKeyByEmailKeepsBody(t *testing.T) {
+	router := newRateLimitedRouter(t)
+
+	w := postLogin(router, `{"email": "jane@example.com", "password": "secret"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "jane@example.com", w.Body.String(), "the handler reads the body the key was taken from")
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/user/tower-tracker-bima/backend/logging"
+)
+
+// RequestIDHeader carries the request ID from clients and proxies and back in responses
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits request IDs accepted from clients, so that they cannot inject into logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestIDMiddleware returns a Gin middleware that assigns every request an ID. An incoming
+// X-Request-ID is kept so that IDs propagate through proxies; otherwise a new UUID is generated.
+// The ID is echoed in the response header and stored in both the Gin and the request context.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(logging.RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Scopes an API key can be granted
+const (
+	ScopeRead            = "read"             // Read access to the routes open to API keys
+	ScopeTowersWrite     = "towers:write"     // Creating and editing towers and their photos
+	ScopeProvidersWrite  = "providers:write"  // Creating, editing and deleting providers; staff keys only
+	ScopeBlankspotsWrite = "blankspots:write" // Creating, editing and deleting blankspot areas; staff keys only
+)
+
+// APIKeyScopes lists every valid scope
+var APIKeyScopes = []string{ScopeRead, ScopeTowersWrite, ScopeProvidersWrite, ScopeBlankspotsWrite}
+
+// APIKey lets integrations call the API on behalf of a user without a login. Only the SHA-256
+// hash of the secret part of the key is stored; deleting the key revokes it.
+type APIKey struct {
+	gorm.Model
+	UserID     uint       `gorm:"index" json:"user_id"` // Owner, whom requests made with the key act as
+	Name       string     `json:"name"`
+	Prefix     string     `gorm:"uniqueIndex" json:"prefix"` // Public part of the key, identifying it in lists and logs
+	KeyHash    string     `json:"-"`
+	Scopes     string     `json:"scopes"` // Comma separated
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range strings.Split(k.Scopes, ",") {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Statuses of a change request
+const (
+	ChangeRequestPending  = "pending"
+	ChangeRequestApproved = "approved"
+	ChangeRequestRejected = "rejected"
+)
+
+// ChangeRequest is a tower edit awaiting approval. Type is the TowerEvent type the edit results in
+// (e.g. "Relocation"); NewData holds the proposed values and OldData the values they replace.
+type ChangeRequest struct {
+	gorm.Model
+	TowerID         uint          `gorm:"index" json:"tower_id"`
+	BaseVersion     uint          `gorm:"not null;default:0" json:"base_version"` // Tower version the change was made against; 0 for requests from before versions
+	Type            string        `gorm:"type:varchar(50)" json:"type"`
+	Status          string        `gorm:"type:varchar(20);index" json:"status"`
+	OldData         string        `gorm:"type:jsonb" json:"old_data"`
+	NewData         string        `gorm:"type:jsonb" json:"new_data"`
+	Photo           *PendingPhoto `gorm:"serializer:json" json:"photo,omitempty"` // Uploaded with the request, applied on approval
+	RequestedBy     uint          `gorm:"index" json:"requested_by"`
+	ReviewedBy      *uint         `json:"reviewed_by"`
+	ReviewedAt      *time.Time    `json:"reviewed_at"`
+	RejectionReason string        `json:"rejection_reason,omitempty"`
+}
+
+// PendingPhoto is a stored photo upload that becomes a TowerPhoto once its change request is approved
+type PendingPhoto struct {
+	URL          string     `json:"url"`
+	MediumURL    string     `json:"medium_url"`
+	ThumbnailURL string     `json:"thumbnail_url"`
+	Caption      string     `json:"caption"`
+	TakenAt      *time.Time `json:"taken_at"` // Given by the uploader, overrides the EXIF capture time
+	IsPrimary    bool       `json:"is_primary"`
+
+	// EXIF metadata read from the original upload
+	ExifLatitude  *float64   `json:"exif_latitude"`
+	ExifLongitude *float64   `json:"exif_longitude"`
+	ExifTakenAt   *time.Time `json:"exif_taken_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Reasons recorded for failed logins
+const (
+	LoginFailureUnknownEmail  = "unknown_email"
+	LoginFailureWrongPassword = "wrong_password"
+	LoginFailureLocked        = "locked"
+	LoginFailureSSODenied     = "sso_denied" // The single sign-on identity maps to no role
+)
+
+// LoginEvent records a single login attempt
+type LoginEvent struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	UserID        *uint     `gorm:"index" json:"user_id"` // Nil when the email does not belong to any user
+	Email         string    `gorm:"index" json:"email"`
+	IP            string    `json:"ip"`
+	UserAgent     string    `json:"user_agent"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty"` // One of the LoginFailure* constants
+	CreatedAt     time.Time `gorm:"index" json:"created_at"`
+}
+
+// SecurityAlertManyIPs is raised when an account is logged into from many IP addresses
+const SecurityAlertManyIPs = "many_login_ips"
+
+// SecurityAlert notifies administrators of suspicious account activity
+type SecurityAlert struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	UserID         uint       `gorm:"index" json:"user_id"`
+	Type           string     `json:"type"`
+	Message        string     `json:"message"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	AcknowledgedBy *uint      `json:"acknowledged_by"`
+	CreatedAt      time.Time  `gorm:"index" json:"created_at"`
+}
@@ -1,13 +1,32 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
 
-// User represents the admin user model
+	"gorm.io/gorm"
+)
+
+// User represents a user account, either staff or an operator of a provider
 type User struct {
 	gorm.Model
 	Name     string `json:"name"`
 	Email    string `json:"email" gorm:"unique"`
 	Password string `json:"-"` // Hide password from JSON responses
+
+	FailedLoginAttempts int        `json:"-"` // Consecutive failed logins, reset by a successful one
+	LockedUntil         *time.Time `json:"-"` // Logins are refused until then
+	PasswordChangedAt   *time.Time `json:"-"` // Set when the password is reset; tokens issued earlier are refused
+
+	// ProviderID links operator accounts to their provider. They may only edit that provider's
+	// towers, and their edits need approval. Staff accounts have no provider.
+	ProviderID *uint `gorm:"index" json:"provider_id"`
+
+	// IsApprover allows reviewing change requests when FOUR_EYES_APPROVAL is enabled
+	IsApprover bool `gorm:"not null;default:false" json:"is_approver"`
+
+	// OIDCSubject is the subject of the single sign-on identity the account is linked to. Linked
+	// accounts have no local password.
+	OIDCSubject *string `gorm:"column:oidc_subject;uniqueIndex" json:"-"`
 }
 
 // Provider represents the telecommunication provider model
@@ -15,30 +34,34 @@ type Provider struct {
 	gorm.Model
 	Name    string   `json:"name" gorm:"unique"`
 	Address string   `json:"address"`
+	Version uint     `json:"version" gorm:"not null;default:1"` // Incremented by every update, served as the ETag
 	Towers  []*Tower `json:"towers,omitempty" gorm:"many2many:provider_towers;"`
 }
 
 // Tower represents the telecommunication tower model
 type Tower struct {
 	gorm.Model
-	PhotoURL    string      `json:"photo_url"`
-	Latitude    float64     `json:"latitude"`
-	Longitude   float64     `json:"longitude"`
-	Kelurahan   string      `json:"kelurahan"`
-	Kecamatan   string      `json:"kecamatan"`
-	Address     string      `json:"address"`
-	Tinggi      float64     `json:"tinggi"`
-	Tipe        string      `json:"tipe"`
-	Status      string      `json:"status" gorm:"default:'active'"`
-	Providers   []*Provider `json:"providers,omitempty" gorm:"many2many:provider_towers;"`
+	PhotoURL  string       `json:"photo_url"`
+	Latitude  float64      `json:"latitude"`
+	Longitude float64      `json:"longitude"`
+	Kelurahan string       `json:"kelurahan"`
+	Kecamatan string       `json:"kecamatan"`
+	Address   string       `json:"address"`
+	Tinggi    float64      `json:"tinggi"`
+	Tipe      string       `json:"tipe"`
+	Status    string       `json:"status" gorm:"default:'active'"`
+	Version   uint         `json:"version" gorm:"not null;default:1"` // Incremented by every update, served as the ETag
+	Providers []*Provider  `json:"providers,omitempty" gorm:"many2many:provider_towers;"`
+	Photos    []TowerPhoto `json:"photos,omitempty"`
 }
 
 // BlankspotArea represents an area on the map with weak signal or no signal
 type BlankspotArea struct {
 	gorm.Model
 	Name        string `json:"name" gorm:"unique"`
-	Kelurahan   string `json:"kelurahan"` // New field
-	Coordinates string `json:"coordinates" gorm:"type:text"` // Store as JSON string: [[lat, lon], [lat, lon], ...]
-	Type        string `json:"type"`                        // e.g., "Blankspot", "Weak Signal"
-	Color       string `json:"color"`                       // e.g., "#FF0000", "#FFFF00"
-}
\ No newline at end of file
+	Kelurahan   string `json:"kelurahan"`                         // New field
+	Coordinates string `json:"coordinates" gorm:"type:text"`      // Store as JSON string: [[lat, lon], [lat, lon], ...]
+	Type        string `json:"type"`                              // e.g., "Blankspot", "Weak Signal"
+	Color       string `json:"color"`                             // e.g., "#FF0000", "#FFFF00"
+	Version     uint   `json:"version" gorm:"not null;default:1"` // Incremented by every update, served as the ETag
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// PasswordResetToken allows setting a new password once, without knowing the current one.
+// Only the SHA-256 hash of the token is stored; the token itself is emailed to the user.
+type PasswordResetToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time // Set once the token was used or superseded
+	CreatedAt time.Time
+}
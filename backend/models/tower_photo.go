@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TowerPhoto represents a single photo of a tower site together with its resized renditions
+type TowerPhoto struct {
+	gorm.Model
+	TowerID           uint       `gorm:"index" json:"tower_id"`
+	URL               string     `json:"url"`           // Full size WebP
+	MediumURL         string     `json:"medium_url"`    // Rendition for detail views
+	ThumbnailURL      string     `json:"thumbnail_url"` // Rendition for lists and map popups
+	Caption           string     `json:"caption"`
+	TakenAt           *time.Time `json:"taken_at"`
+	Latitude          *float64   `json:"latitude"`            // EXIF GPS position, if present
+	Longitude         *float64   `json:"longitude"`           // EXIF GPS position, if present
+	DistanceFromTower *float64   `json:"distance_from_tower"` // Meters between the EXIF position and the tower
+	FarFromTower      bool       `json:"far_from_tower"`      // Set when taken further away than PHOTO_MAX_DISTANCE_METERS
+	UploadedBy        uint       `json:"uploaded_by"`         // ID of the user who uploaded the photo
+	IsPrimary         bool       `json:"is_primary"`          // Mirrored into Tower.PhotoURL
+	SortOrder         int        `json:"sort_order"`
+}
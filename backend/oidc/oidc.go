@@ -0,0 +1,241 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/user/tower-tracker-bima/backend/config"
+)
+
+// maxResponseBytes bounds the documents read from the identity provider
+const maxResponseBytes = 1 << 20
+
+// Claims are the identity claims of a verified ID token
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+// discoveryDocument holds the parts of the provider metadata the authorization code flow needs
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to an OpenID Connect identity provider. Its metadata and signing keys are fetched
+// on first use and cached.
+type Provider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+	keys      *keySet
+}
+
+// Default is the identity provider used for single sign-on, set up by InitOIDC. It is nil when
+// single sign-on is disabled.
+var Default *Provider
+
+// InitOIDC sets up Default from the OIDC section of config.Current
+func InitOIDC() {
+	cfg := config.Current.OIDC
+	if !cfg.Enabled() {
+		Default = nil
+		return
+	}
+	Default = NewProvider(cfg)
+	log.Printf("Single sign-on enabled with issuer %s.", cfg.IssuerURL)
+}
+
+// NewProvider creates a provider for the identity provider described by cfg
+func NewProvider(cfg config.OIDCConfig) *Provider {
+	return &Provider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// AuthCodeURL returns the URL of the identity provider's login page. The code challenge is derived
+// from verifier, which must be passed to Exchange along with the returned code.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code at the token endpoint and returns the raw ID token
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &token)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	if token.Error != "" {
+		return "", fmt.Errorf("token request failed: %s %s", token.Error, token.ErrorDescription)
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status %d", status)
+	}
+	if token.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return token.IDToken, nil
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an ID token and returns its claims
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, discovery.JWKSURI, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+
+	result := &Claims{}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string: // Some providers send it as a string
+		result.EmailVerified = verified == "true"
+	}
+	switch groups := claims[p.cfg.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				result.Groups = append(result.Groups, name)
+			}
+		}
+	case string:
+		result.Groups = []string{groups}
+	}
+	if result.Subject == "" {
+		return nil, errors.New("invalid ID token: missing sub claim")
+	}
+	return result, nil
+}
+
+// metadata returns the provider metadata, fetching it from the discovery endpoint the first time
+func (p *Provider) metadata(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.IssuerURL+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var discovery discoveryDocument
+	status, err := p.doJSON(req, &discovery)
+	if err == nil && status != http.StatusOK {
+		err = fmt.Errorf("status %d", status)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenID configuration: %w", err)
+	}
+
+	// The issuer must match exactly, as ID tokens are checked against it (OpenID Connect Discovery 4.3)
+	if discovery.Issuer != p.cfg.IssuerURL {
+		return nil, fmt.Errorf("OpenID configuration is for issuer %q instead of %q", discovery.Issuer, p.cfg.IssuerURL)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("OpenID configuration lacks authorization_endpoint, token_endpoint or jwks_uri")
+	}
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// doJSON sends req and decodes the JSON response body into v, returning the status code
+func (p *Provider) doJSON(req *http.Request, v interface{}) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return resp.StatusCode, fmt.Errorf("invalid JSON response (status %d): %w", resp.StatusCode, err)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,150 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/oidc"
+	"github.com/user/tower-tracker-bima/backend/oidc/oidctest"
+)
+
+const clientID = "tower-tracker"
+
+func newProvider(t *testing.T) (*oidc.Provider, *oidctest.Server) {
+	server := oidctest.NewServer(t, clientID)
+	provider := oidc.NewProvider(config.OIDCConfig{
+		IssuerURL:   server.Issuer(),
+		ClientID:    clientID,
+		RedirectURL: "http://localhost:8080/api/auth/oidc/callback",
+		Scopes:      []string{"openid", "email"},
+		GroupsClaim: "groups",
+	})
+	return provider, server
+}
+
+// signIn runs the authorization code flow up to the verified claims
+func signIn(t *testing.T, provider *oidc.Provider, server *oidctest.Server, claims jwt.MapClaims, key *rsa.PrivateKey) (*oidc.Claims, error) {
+	ctx := context.Background()
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+	require.NoError(t, err)
+
+	code, state, err := server.AuthorizeWithKey(authURL, claims, key)
+	require.NoError(t, err)
+	assert.Equal(t, "state-1", state)
+
+	rawIDToken, err := provider.Exchange(ctx, code, "verifier-1")
+	require.NoError(t, err)
+	return provider.Verify(ctx, rawIDToken, "nonce-1")
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	provider, server := newProvider(t)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", "verifier-1")
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	query := parsed.Query()
+	assert.Equal(t, clientID, query.Get("client_id"))
+	assert.Equal(t, "openid email", query.Get("scope"))
+	assert.Equal(t, "state-1", query.Get("state"))
+	assert.Equal(t, "nonce-1", query.Get("nonce"))
+	assert.Equal(t, oidc.CodeChallenge("verifier-1"), query.Get("code_challenge"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+}
+
+func TestSignIn(t *testing.T) {
+	provider, server := newProvider(t)
+
+	claims, err := signIn(t, provider, server, jwt.MapClaims{
+		"sub":            "user-1",
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"name":           "Jane",
+		"groups":         []string{"staff", "approvers"},
+	}, server.Key)
+	require.NoError(t, err)
+	assert.Equal(t, &oidc.Claims{
+		Subject:       "user-1",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Name:          "Jane",
+		Groups:        []string{"staff", "approvers"},
+	}, claims)
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	provider, server := newProvider(t)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+	require.NoError(t, err)
+	code, _, err := server.Authorize(authURL, jwt.MapClaims{"sub": "user-1"})
+	require.NoError(t, err)
+
+	_, err = provider.Exchange(ctx, code, "another-verifier")
+	assert.ErrorContains(t, err, "invalid_grant")
+}
+
+func TestVerifyRejects(t *testing.T) {
+	provider, server := newProvider(t)
+	ctx := context.Background()
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		key    *rsa.PrivateKey
+		err    string
+	}{
+		{"nonce mismatch", jwt.MapClaims{"nonce": "nonce-2"}, server.Key, "nonce mismatch"},
+		{"missing nonce", jwt.MapClaims{"nonce": nil}, server.Key, "nonce mismatch"},
+		{"bad signature", jwt.MapClaims{}, otherKey, "signature is invalid"},
+		{"wrong issuer", jwt.MapClaims{"iss": "https://evil.example.com"}, server.Key, "invalid issuer"},
+		{"wrong audience", jwt.MapClaims{"aud": "another-client"}, server.Key, "invalid audience"},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, server.Key, "token is expired"},
+		{"missing subject", jwt.MapClaims{"sub": nil}, server.Key, "missing sub claim"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := jwt.MapClaims{"sub": "user-1", "nonce": "nonce-1"}
+			for name, value := range tt.claims {
+				if value == nil {
+					delete(claims, name)
+				} else {
+					claims[name] = value
+				}
+			}
+			rawIDToken, err := server.SignIDTokenWithKey(claims, tt.key)
+			require.NoError(t, err)
+
+			_, err = provider.Verify(ctx, rawIDToken, "nonce-1")
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	server := oidctest.NewServer(t, clientID)
+	// The same server under another name publishes a different issuer than the one configured
+	parsed, err := url.Parse(server.Issuer())
+	require.NoError(t, err)
+	provider := oidc.NewProvider(config.OIDCConfig{
+		IssuerURL: "http://localhost:" + parsed.Port(),
+		ClientID:  clientID,
+	})
+
+	_, err = provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", "verifier-1")
+	assert.ErrorContains(t, err, "OpenID configuration is for issuer")
+}
@@ -0,0 +1,195 @@
+// Package oidctest provides a mock OpenID Connect identity provider for tests. It serves the
+// discovery document, a JWK set and a token endpoint that checks the PKCE code verifier.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyID is the ID of the signing key published by the server
+const KeyID = "test-key"
+
+// Server is a mock identity provider. Codes are issued by Authorize, which stands in for the user
+// signing in at the login page, and redeemed once at the token endpoint.
+type Server struct {
+	*httptest.Server
+	ClientID string
+	Key      *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+// grant is an issued authorization code
+type grant struct {
+	idToken       string
+	codeChallenge string
+	redirectURI   string
+}
+
+// NewServer starts an identity provider for clientID that is closed when the test ends
+func NewServer(t testing.TB, clientID string) *Server {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{ClientID: clientID, Key: key, codes: map[string]grant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	mux.HandleFunc("POST /token", s.token)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Issuer returns the issuer URL of the server
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// Authorize signs in at the login page the client sent the user to. It issues a code for an ID
+// token with claims, filling in the standard claims and the nonce of the request unless set, and
+// returns the code and state the identity provider redirects back with.
+func (s *Server) Authorize(authURL string, claims jwt.MapClaims) (code, state string, err error) {
+	return s.AuthorizeWithKey(authURL, claims, s.Key)
+}
+
+// AuthorizeWithKey is Authorize with the ID token signed by key under the published key ID
+func (s *Server) AuthorizeWithKey(authURL string, claims jwt.MapClaims, key *rsa.PrivateKey) (code, state string, err error) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != s.ClientID {
+		return "", "", fmt.Errorf("unknown client_id %q", query.Get("client_id"))
+	}
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" {
+		return "", "", fmt.Errorf("unsupported authorization request %s", parsed.RawQuery)
+	}
+
+	withNonce := jwt.MapClaims{"nonce": query.Get("nonce")}
+	for name, value := range claims {
+		withNonce[name] = value
+	}
+	idToken, err := s.SignIDTokenWithKey(withNonce, key)
+	if err != nil {
+		return "", "", err
+	}
+
+	code = randomString()
+	s.mu.Lock()
+	s.codes[code] = grant{idToken: idToken, codeChallenge: query.Get("code_challenge"), redirectURI: query.Get("redirect_uri")}
+	s.mu.Unlock()
+	return code, query.Get("state"), nil
+}
+
+// SignIDToken signs an ID token with the server key. The iss, aud, iat and exp claims are filled
+// in unless set in claims.
+func (s *Server) SignIDToken(claims jwt.MapClaims) (string, error) {
+	return s.SignIDTokenWithKey(claims, s.Key)
+}
+
+// SignIDTokenWithKey is SignIDToken with a different key, to test signature checks
+func (s *Server) SignIDTokenWithKey(claims jwt.MapClaims, key *rsa.PrivateKey) (string, error) {
+	now := time.Now()
+	full := jwt.MapClaims{
+		"iss": s.Issuer(),
+		"aud": s.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	for name, value := range claims {
+		full[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, full)
+	token.Header["kid"] = KeyID
+	return token.SignedString(key)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.Issuer(),
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	public := s.Key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": KeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	s.mu.Lock()
+	code := r.PostForm.Get("code")
+	issued, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code":
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+	case r.PostForm.Get("client_id") != s.ClientID:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+	case !ok || r.PostForm.Get("redirect_uri") != issued.redirectURI:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+	case challenge(r.PostForm.Get("code_verifier")) != issued.codeChallenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"id_token": issued.idToken, "token_type": "Bearer"})
+	}
+}
+
+// challenge derives the S256 code challenge of a PKCE code verifier (RFC 7636)
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
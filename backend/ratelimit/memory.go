@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter keeps limiter state in process memory. It tracks at most maxKeys keys, evicting
+// the least recently used ones first; keys whose limit has fully replenished are dropped as well,
+// since they carry no state. Limits are per process, so use RedisLimiter with several instances.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	maxKeys int
+	entries map[string]*list.Element
+	order   *list.List // Most recently used first
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	key string
+	tat time.Time // Theoretical arrival time of the next request
+}
+
+// NewMemoryLimiter returns a limiter tracking at most maxKeys keys
+func NewMemoryLimiter(maxKeys int) *MemoryLimiter {
+	return &MemoryLimiter{
+		maxKeys: maxKeys,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	tat := now
+	element, found := m.entries[key]
+	if found {
+		if entryTAT := element.Value.(*memoryEntry).tat; entryTAT.After(now) {
+			tat = entryTAT
+		}
+	}
+
+	newTAT := tat.Add(limit.interval())
+	allowAt := newTAT.Add(-limit.Period)
+	if now.Before(allowAt) {
+		return result(limit, false, allowAt.Sub(now), tat.Sub(now)), nil
+	}
+
+	if found {
+		element.Value.(*memoryEntry).tat = newTAT
+		m.order.MoveToFront(element)
+	} else {
+		m.entries[key] = m.order.PushFront(&memoryEntry{key: key, tat: newTAT})
+		m.evict(now)
+	}
+	return result(limit, true, 0, newTAT.Sub(now)), nil
+}
+
+// evict drops the least recently used keys while there are too many, and replenished ones from the back
+func (m *MemoryLimiter) evict(now time.Time) {
+	for back := m.order.Back(); back != nil; back = m.order.Back() {
+		entry := back.Value.(*memoryEntry)
+		if len(m.entries) <= m.maxKeys && entry.tat.After(now) {
+			return
+		}
+		m.order.Remove(back)
+		delete(m.entries, entry.key)
+	}
+}
+
+// Len returns the number of tracked keys
+func (m *MemoryLimiter) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStart is when the clocks of the tests start
+var testStart = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeClock is a manually advanced clock for MemoryLimiter.now
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestMemoryLimiter(maxKeys int) (*MemoryLimiter, *fakeClock) {
+	clock := &fakeClock{now: testStart}
+	limiter := NewMemoryLimiter(maxKeys)
+	limiter.now = clock.Now
+	return limiter, clock
+}
+
+// testGCRA checks the generic cell rate algorithm of a limiter. advance moves its clock forward.
+func testGCRA(t *testing.T, limiter Limiter, advance func(time.Duration)) {
+	ctx := context.Background()
+	limit := Limit{Count: 3, Period: time.Minute} // One request earned back every 20 seconds
+
+	// The full count may be used at once
+	for i, want := range []Result{
+		{Allowed: true, Limit: 3, Remaining: 2, ResetAfter: 20 * time.Second},
+		{Allowed: true, Limit: 3, Remaining: 1, ResetAfter: 40 * time.Second},
+		{Allowed: true, Limit: 3, Remaining: 0, ResetAfter: time.Minute},
+	} {
+		got, err := limiter.Allow(ctx, "client", limit)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "request %d", i+1)
+	}
+
+	got, err := limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: false, Limit: 3, RetryAfter: 20 * time.Second, ResetAfter: time.Minute}, got)
+
+	// Denied requests do not use up capacity, so retrying after Retry-After succeeds
+	advance(5 * time.Second)
+	got, err = limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.False(t, got.Allowed)
+	assert.Equal(t, 15*time.Second, got.RetryAfter)
+
+	advance(15 * time.Second)
+	got, err = limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 3, Remaining: 0, ResetAfter: time.Minute}, got)
+
+	// Other keys are limited separately
+	got, err = limiter.Allow(ctx, "other", limit)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Remaining)
+
+	// After the period, the full count is available again
+	advance(time.Minute)
+	got, err = limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 3, Remaining: 2, ResetAfter: 20 * time.Second}, got)
+}
+
+func TestMemoryLimiterGCRA(t *testing.T) {
+	limiter, clock := newTestMemoryLimiter(100)
+	testGCRA(t, limiter, clock.Advance)
+}
+
+func TestMemoryLimiterEvictsLeastRecentlyUsed(t *testing.T) {
+	limiter, _ := newTestMemoryLimiter(2)
+	ctx := context.Background()
+	limit := Limit{Count: 2, Period: time.Hour}
+
+	for _, key := range []string{"a", "b", "a", "c"} {
+		_, err := limiter.Allow(ctx, key, limit)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, limiter.Len())
+
+	// "b" was used least recently and is evicted; "a" keeps its state
+	got, err := limiter.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, got.Allowed)
+
+	got, err = limiter.Allow(ctx, "b", limit)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Remaining, "evicted keys start over")
+}
+
+func TestMemoryLimiterDropsReplenishedKeys(t *testing.T) {
+	limiter, clock := newTestMemoryLimiter(100)
+	ctx := context.Background()
+	limit := Limit{Count: 5, Period: time.Second}
+
+	_, err := limiter.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	clock.Advance(time.Second)
+	_, err = limiter.Allow(ctx, "b", limit)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, limiter.Len())
+}
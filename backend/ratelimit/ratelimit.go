@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/user/tower-tracker-bima/backend/config"
+)
+
+// Limit allows Count requests per Period. Requests may burst up to Count at once, after which
+// they are let through evenly, one every Period/Count.
+type Limit struct {
+	Count  int
+	Period time.Duration
+}
+
+// interval is the time it takes to earn back one request
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Count)
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Count, l.Period)
+}
+
+// Result is the outcome of a rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      int           // Count of the limit that was checked
+	Remaining  int           // Requests that may still be made right away
+	RetryAfter time.Duration // When denied, how long until the next request is allowed
+	ResetAfter time.Duration // How long until the full Count is available again
+}
+
+// Limiter checks requests against limits. Keys identify what is limited, e.g. "login_ip:10.0.0.1".
+type Limiter interface {
+	// Allow records a request for key and reports whether it is within limit
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Default is the limiter used by the rate limiting middleware, set up by InitLimiter
+var Default Limiter
+
+// InitLimiter sets up Default from the rate limit section of config.Current
+func InitLimiter() {
+	cfg := config.Current.RateLimit
+	switch cfg.Backend {
+	case "redis":
+		options, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			log.Fatalf("Invalid REDIS_URL: %v", err)
+		}
+		Default = NewRedisLimiter(redis.NewClient(options), "tower:ratelimit:")
+	default:
+		Default = NewMemoryLimiter(cfg.MaxKeys)
+	}
+	log.Printf("Rate limiter initialized using the %s backend.", cfg.Backend)
+}
+
+// result builds a Result from the generic cell rate algorithm state shared by all backends:
+// retryAfter is how long until a denied request would be allowed, resetAfter how long until
+// the limit is fully replenished.
+func result(limit Limit, allowed bool, retryAfter, resetAfter time.Duration) Result {
+	res := Result{Allowed: allowed, Limit: limit.Count, ResetAfter: resetAfter}
+	if !allowed {
+		res.RetryAfter = retryAfter
+		return res
+	}
+	// The capacity not yet used up, in whole requests
+	res.Remaining = int(math.Floor(float64(limit.Period-resetAfter) / float64(limit.interval())))
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	return res
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript applies the generic cell rate algorithm atomically. It stores the theoretical arrival
+// time (in microseconds of the Redis clock, so that instances with skewed clocks agree) under the key
+// until the limit has fully replenished. It returns {allowed, retry_after_us, reset_after_us}.
+var gcraScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - period
+if now < allow_at then
+	return {0, allow_at - now, tat - now}
+end
+
+redis.call("SET", KEYS[1], new_tat, "PX", math.ceil((new_tat - now) / 1000))
+return {1, 0, new_tat - now}
+`)
+
+// RedisLimiter keeps limiter state in Redis (or any server speaking its protocol with Lua scripting),
+// so that limits hold across all instances. Any redis.Scripter works, including clients connected
+// to an in-process fake such as miniredis.
+type RedisLimiter struct {
+	client redis.Scripter
+	prefix string // Prepended to every key
+}
+
+// NewRedisLimiter returns a limiter storing its keys in client under prefix
+func NewRedisLimiter(client redis.Scripter, prefix string) *RedisLimiter {
+	return &RedisLimiter{client: client, prefix: prefix}
+}
+
+func (r *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	values, err := gcraScript.Run(ctx, r.client, []string{r.prefix + key},
+		limit.interval().Microseconds(), limit.Period.Microseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit check failed: %w", err)
+	}
+	if len(values) != 3 {
+		return Result{}, fmt.Errorf("rate limit check failed: unexpected reply %v", values)
+	}
+	return result(limit, values[0] == 1, time.Duration(values[1])*time.Microsecond, time.Duration(values[2])*time.Microsecond), nil
+}
@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisLimiter(t *testing.T) (*RedisLimiter, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	server.SetTime(testStart)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisLimiter(client, "test:"), server
+}
+
+func TestRedisLimiterGCRA(t *testing.T) {
+	limiter, server := newTestRedisLimiter(t)
+	now := testStart
+	testGCRA(t, limiter, func(d time.Duration) {
+		now = now.Add(d)
+		server.SetTime(now)
+		server.FastForward(d)
+	})
+}
+
+func TestRedisLimiterExpiresKeys(t *testing.T) {
+	limiter, server := newTestRedisLimiter(t)
+	limit := Limit{Count: 3, Period: time.Minute}
+
+	_, err := limiter.Allow(context.Background(), "client", limit)
+	require.NoError(t, err)
+	require.True(t, server.Exists("test:client"), "keys are stored under the prefix")
+	assert.Equal(t, 20*time.Second, server.TTL("test:client"), "keys live until the limit has replenished")
+
+	server.FastForward(20 * time.Second)
+	assert.False(t, server.Exists("test:client"))
+}
+
+func TestRedisLimiterReportsErrors(t *testing.T) {
+	limiter, server := newTestRedisLimiter(t)
+	server.Close()
+
+	_, err := limiter.Allow(context.Background(), "client", Limit{Count: 3, Period: time.Minute})
+	assert.ErrorContains(t, err, "rate limit check failed")
+}
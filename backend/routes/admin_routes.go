@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/controllers"
+	"github.com/user/tower-tracker-bima/backend/middleware"
+)
+
+func AdminRoutes(router *gin.Engine) {
+	// Authorized routes
+	admin := router.Group("/api/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequireStaff())
+	{
+		// Backups hold password hashes, reset tokens and API key hashes
+		admin.GET("/backup", middleware.RequireApprover(), controllers.DownloadBackup)
+		admin.GET("/security-alerts", controllers.GetSecurityAlerts)
+		admin.PUT("/security-alerts/:id/acknowledge", controllers.AcknowledgeSecurityAlert)
+		admin.GET("/users", controllers.GetUsers)
+		admin.PUT("/users/:id/provider", controllers.SetUserProvider)
+		admin.PUT("/users/:id/approver", controllers.SetUserApprover)
+	}
+}
@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/storage"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+func TestBackupDownloadRequiresApprover(t *testing.T) {
+	testutil.Setup(t, map[string]string{"STORAGE_DRIVER": "memory"})
+	previous := storage.Default
+	t.Cleanup(func() { storage.Default = previous })
+	storage.InitStorage()
+	router := gin.New()
+	AdminRoutes(router)
+
+	download := func(user models.User) int {
+		require.NoError(t, database.DB.Create(&user).Error)
+		token, err := helper.GenerateJWT(user.ID)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/backup", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, download(models.User{Name: "Staff", Email: "staff@example.com", Password: "x"}))
+	assert.Equal(t, http.StatusOK, download(models.User{Name: "Approver", Email: "approver@example.com", Password: "x", IsApprover: true}))
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/controllers"
+	"github.com/user/tower-tracker-bima/backend/middleware"
+)
+
+func APIKeyRoutes(router *gin.Engine) {
+	// Authorized routes; API keys cannot manage API keys
+	apiKeys := router.Group("/api/api-keys")
+	apiKeys.Use(middleware.AuthMiddleware())
+	{
+		apiKeys.GET("", controllers.GetAPIKeys)
+		apiKeys.POST("", controllers.CreateAPIKey)
+		apiKeys.DELETE("/:id", controllers.DeleteAPIKey)
+	}
+}
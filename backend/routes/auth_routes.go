@@ -2,19 +2,46 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/config"
 	"github.com/user/tower-tracker-bima/backend/controllers"
 	"github.com/user/tower-tracker-bima/backend/middleware" // Import middleware
+	"github.com/user/tower-tracker-bima/backend/ratelimit"
 )
 
 func AuthRoutes(router *gin.Engine) {
+	limits := config.Current.RateLimit
+	loginLimit := middleware.RateLimitMiddleware(
+		middleware.RateLimitPolicy{Name: "login_ip", Limit: ratelimit.Limit(limits.LoginPerIP), Key: middleware.KeyByIP},
+		middleware.RateLimitPolicy{Name: "login_email", Limit: ratelimit.Limit(limits.LoginPerEmail), Key: middleware.KeyByEmail},
+	)
+	changePasswordLimit := middleware.RateLimitMiddleware(
+		middleware.RateLimitPolicy{Name: "change_password_user", Limit: ratelimit.Limit(limits.ChangePasswordPerUser), Key: middleware.KeyByUser},
+	)
+	forgotPasswordLimit := middleware.RateLimitMiddleware(
+		middleware.RateLimitPolicy{Name: "forgot_password_ip", Limit: ratelimit.Limit(limits.PasswordReset), Key: middleware.KeyByIP},
+		middleware.RateLimitPolicy{Name: "forgot_password_email", Limit: ratelimit.Limit(limits.PasswordReset), Key: middleware.KeyByEmail},
+	)
+	resetPasswordLimit := middleware.RateLimitMiddleware(
+		middleware.RateLimitPolicy{Name: "reset_password_ip", Limit: ratelimit.Limit(limits.PasswordReset), Key: middleware.KeyByIP},
+	)
+
 	auth := router.Group("/api/auth")
 	{
 		// Apply rate limiting to login and change-password
-		auth.POST("/login", middleware.RateLimitMiddleware(5, 1), controllers.Login)
-		auth.POST("/register", controllers.Register)
+		auth.POST("/login", loginLimit, controllers.Login)
+		// Accounts without a provider are staff accounts, so only staff may create them. The first
+		// account is created with the create-admin command.
+		auth.POST("/register", middleware.AuthMiddleware(), middleware.RequireStaff(), controllers.Register)
+		auth.POST("/forgot-password", forgotPasswordLimit, controllers.ForgotPassword)
+		auth.POST("/reset-password", resetPasswordLimit, controllers.ResetPassword)
+
+		// Single sign-on through the configured OpenID Connect provider
+		auth.GET("/oidc/login", controllers.OIDCLogin)
+		auth.GET("/oidc/callback", controllers.OIDCCallback)
 
 		// Authenticated routes
 		auth.Use(middleware.AuthMiddleware()) // Apply auth middleware to subsequent routes in this group
-		auth.PUT("/change-password", middleware.RateLimitMiddleware(5, 1), controllers.ChangePassword) // New route for changing password
+		auth.PUT("/change-password", changePasswordLimit, controllers.ChangePassword) // New route for changing password
+		auth.GET("/sessions", controllers.GetSessions)
 	}
 }
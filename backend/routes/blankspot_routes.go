@@ -4,19 +4,22 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/user/tower-tracker-bima/backend/controllers"
 	"github.com/user/tower-tracker-bima/backend/middleware"
+	"github.com/user/tower-tracker-bima/backend/models"
 )
 
 func BlankspotRoutes(router *gin.Engine) {
 	// Public routes (if any, though blankspots are likely admin-managed)
 	router.GET("/api/blankspots", controllers.GetBlankspotAreas)
 	router.GET("/api/blankspots/:id", controllers.GetBlankspotArea)
+	router.GET("/api/blankspots/:id/towers", controllers.GetBlankspotTowers)
 
-	// Authorized routes
+	// Authorized routes, also open to API keys of staff accounts with the blankspots:write scope
 	authorized := router.Group("/api/blankspots")
-	authorized.Use(middleware.AuthMiddleware())
+	authorized.Use(middleware.AllowAPIKeys(models.ScopeRead, models.ScopeBlankspotsWrite), middleware.AuthMiddleware(), middleware.RequireStaff())
 	{
 		authorized.POST("", controllers.CreateBlankspotArea)
 		authorized.PUT("/:id", controllers.UpdateBlankspotArea)
+		authorized.PATCH("/:id", controllers.PatchBlankspotArea)
 		authorized.DELETE("/:id", controllers.DeleteBlankspotArea)
 	}
 }
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/controllers"
+	"github.com/user/tower-tracker-bima/backend/middleware"
+)
+
+func ChangeRequestRoutes(router *gin.Engine) {
+	// Authorized routes; operator accounts see their own requests, staff review them
+	changeRequests := router.Group("/api/change-requests")
+	changeRequests.Use(middleware.AuthMiddleware())
+	{
+		changeRequests.GET("", controllers.GetChangeRequests)
+		changeRequests.GET("/queue", middleware.RequireStaff(), controllers.GetChangeRequestQueue)
+		changeRequests.GET("/:id", controllers.GetChangeRequest)
+		changeRequests.PUT("/:id/approve", middleware.RequireStaff(), controllers.ApproveChangeRequest)
+		changeRequests.PUT("/:id/reject", middleware.RequireStaff(), controllers.RejectChangeRequest)
+	}
+}
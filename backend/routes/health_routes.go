@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/controllers"
+)
+
+func HealthRoutes(router *gin.Engine) {
+	// Probes for the orchestrator, registered before the SPA fallback so they never return index.html
+	router.GET("/healthz", controllers.Healthz)
+	router.GET("/readyz", controllers.Readyz)
+	router.GET("/version", controllers.Version)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/middleware"
+)
+
+func MetricsRoutes(router *gin.Engine) {
+	// Prometheus scrape endpoint, protected by METRICS_TOKEN when it is set
+	handlers := []gin.HandlerFunc{}
+	if token := config.Current.Metrics.Token; token != "" {
+		handlers = append(handlers, middleware.MetricsTokenMiddleware(token))
+	}
+	router.GET("/metrics", append(handlers, gin.WrapH(promhttp.Handler()))...)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/user/tower-tracker-bima/backend/controllers"
 	"github.com/user/tower-tracker-bima/backend/middleware"
+	"github.com/user/tower-tracker-bima/backend/models"
 )
 
 func ProviderRoutes(router *gin.Engine) {
@@ -11,12 +12,13 @@ func ProviderRoutes(router *gin.Engine) {
 	router.GET("/api/providers", controllers.GetProviders)
 	router.GET("/api/providers/:id", controllers.GetProvider)
 
-	// Authorized routes
+	// Authorized routes, also open to API keys of staff accounts with the providers:write scope
 	authorized := router.Group("/api/providers")
-	authorized.Use(middleware.AuthMiddleware())
+	authorized.Use(middleware.AllowAPIKeys(models.ScopeRead, models.ScopeProvidersWrite), middleware.AuthMiddleware(), middleware.RequireStaff())
 	{
 		authorized.POST("", controllers.CreateProvider)
 		authorized.PUT("/:id", controllers.UpdateProvider)
+		authorized.PATCH("/:id", controllers.PatchProvider)
 		authorized.DELETE("/:id", controllers.DeleteProvider)
 	}
 }
@@ -4,25 +4,37 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/user/tower-tracker-bima/backend/controllers"
 	"github.com/user/tower-tracker-bima/backend/middleware"
+	"github.com/user/tower-tracker-bima/backend/models"
 )
 
 func TowerRoutes(router *gin.Engine) {
 	// Public routes
 	router.GET("/api/towers", controllers.GetTowers)
 	router.GET("/api/towers/:id", controllers.GetTower)
+	router.GET("/api/towers/:id/photos", controllers.GetTowerPhotos)
 
-	// Authorized routes
+	// Authorized routes, also open to API keys with the towers:write scope. Operator accounts may
+	// only edit and add photos to their provider's towers, the other changes are left to staff.
 	authorized := router.Group("/api/towers")
-	authorized.Use(middleware.AuthMiddleware())
+	authorized.Use(middleware.AllowAPIKeys(models.ScopeRead, models.ScopeTowersWrite), middleware.AuthMiddleware())
+	staffOnly := middleware.RequireStaff()
 	{
-		authorized.POST("", controllers.CreateTower)
+		authorized.POST("", staffOnly, controllers.CreateTower)
+		authorized.POST("/photo-metadata", controllers.ExtractPhotoMetadata)
 		authorized.PUT("/:id", controllers.UpdateTower)
-		authorized.DELETE("/:id", controllers.DeleteTower)
+		authorized.PATCH("/:id", controllers.PatchTower)
+		authorized.DELETE("/:id", staffOnly, controllers.DeleteTower)
 
 		// New routes for timeline events
 		authorized.PUT("/:id/ownership", controllers.ChangeOwnership)
 		authorized.PUT("/:id/relocate", controllers.RelocateTower)
-		authorized.PUT("/:id/dismantle", controllers.DismantleTower)
+		authorized.PUT("/:id/dismantle", staffOnly, controllers.DismantleTower)
 		authorized.GET("/:id/history", controllers.GetTowerHistory)
+
+		// Tower photo gallery
+		authorized.POST("/:id/photos", controllers.UploadTowerPhoto)
+		authorized.PUT("/:id/photos/order", staffOnly, controllers.ReorderTowerPhotos)
+		authorized.PUT("/:id/photos/:photo_id", staffOnly, controllers.UpdateTowerPhoto)
+		authorized.DELETE("/:id/photos/:photo_id", staffOnly, controllers.DeleteTowerPhoto)
 	}
 }
@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/database"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/models"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+// createAPIKeyOf stores a key of user with scopes and returns its secret
+func createAPIKeyOf(t *testing.T, user models.User, scopes string) string {
+	key, prefix, hash, err := helper.GenerateAPIKey()
+	require.NoError(t, err)
+	require.NoError(t, database.DB.Create(&models.APIKey{UserID: user.ID, Name: "Integration", Prefix: prefix, KeyHash: hash, Scopes: scopes}).Error)
+	return key
+}
+
+// sendWithAPIKey sends a JSON merge patch, or nothing if body is empty, to router with key in the
+// X-API-Key header and the ETag of version in If-Match
+func sendWithAPIKey(router *gin.Engine, method, path, key, body string, version uint) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-API-Key", key)
+	req.Header.Set("If-Match", helper.ETag(version))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOperatorAPIKeyLimitedToItsProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testutil.Setup(t, nil)
+	router := gin.New()
+	TowerRoutes(router)
+
+	own := models.Provider{Name: "Operator A"}
+	other := models.Provider{Name: "Operator B"}
+	require.NoError(t, database.DB.Create(&own).Error)
+	require.NoError(t, database.DB.Create(&other).Error)
+	ownTower := models.Tower{Kelurahan: "Paruga", Status: "active", Providers: []*models.Provider{&own}}
+	otherTower := models.Tower{Kelurahan: "Sadia", Status: "active", Providers: []*models.Provider{&other}}
+	require.NoError(t, database.DB.Create(&ownTower).Error)
+	require.NoError(t, database.DB.Create(&otherTower).Error)
+
+	operator := models.User{Name: "Operator", Email: "operator@example.com", Password: "x", ProviderID: &own.ID}
+	require.NoError(t, database.DB.Create(&operator).Error)
+	key := createAPIKeyOf(t, operator, models.ScopeRead+","+models.ScopeTowersWrite)
+
+	w := sendWithAPIKey(router, http.MethodPatch, fmt.Sprintf("/api/towers/%d", otherTower.ID), key, `{"address": "Jl. Sudirman"}`, otherTower.Version)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "own provider")
+
+	w = sendWithAPIKey(router, http.MethodDelete, fmt.Sprintf("/api/towers/%d", ownTower.ID), key, "", ownTower.Version)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "restricted to staff accounts")
+
+	// Edits of its own towers are submitted for approval, like those of the operator
+	w = sendWithAPIKey(router, http.MethodPatch, fmt.Sprintf("/api/towers/%d", ownTower.ID), key, `{"address": "Jl. Sudirman"}`, ownTower.Version)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var request models.ChangeRequest
+	require.NoError(t, database.DB.First(&request).Error)
+	assert.Equal(t, ownTower.ID, request.TowerID)
+	assert.Equal(t, operator.ID, request.RequestedBy)
+}
+
+func TestProviderAndBlankspotRoutesAcceptAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testutil.Setup(t, nil)
+	router := gin.New()
+	ProviderRoutes(router)
+	BlankspotRoutes(router)
+
+	staff := models.User{Name: "Staff", Email: "staff@example.com", Password: "x"}
+	require.NoError(t, database.DB.Create(&staff).Error)
+	providersKey := createAPIKeyOf(t, staff, models.ScopeRead+","+models.ScopeProvidersWrite)
+	provider := models.Provider{Name: "Operator A"}
+	require.NoError(t, database.DB.Create(&provider).Error)
+	area := models.BlankspotArea{Name: "Paruga", Kelurahan: "Paruga", Coordinates: "[[-8.45, 118.72]]", Type: "Blankspot", Color: "#FF0000"}
+	require.NoError(t, database.DB.Create(&area).Error)
+
+	w := sendWithAPIKey(router, http.MethodPatch, fmt.Sprintf("/api/providers/%d", provider.ID), providersKey, `{"address": "Jl. Sudirman"}`, provider.Version)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = sendWithAPIKey(router, http.MethodPatch, fmt.Sprintf("/api/blankspots/%d", area.ID), providersKey, `{"name": "Paruga Selatan"}`, area.Version)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "lacks the blankspots:write scope")
+
+	blankspotsKey := createAPIKeyOf(t, staff, models.ScopeBlankspotsWrite)
+	w = sendWithAPIKey(router, http.MethodPatch, fmt.Sprintf("/api/blankspots/%d", area.ID), blankspotsKey, `{"name": "Paruga Selatan"}`, area.Version)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
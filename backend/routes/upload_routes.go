@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/config"
+	"github.com/user/tower-tracker-bima/backend/controllers"
+)
+
+func UploadRoutes(router *gin.Engine) {
+	// Uploaded photos, served from the configured storage backend under the path of UPLOAD_URL_PREFIX
+	uploads := config.Current.Storage.URLPath() + "/*filepath"
+	router.GET(uploads, controllers.ServeUpload)
+	router.HEAD(uploads, controllers.ServeUpload)
+}
@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/storage"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+func TestUploadRoutesFollowURLPrefix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testutil.LoadConfig(t, map[string]string{
+		"STORAGE_DRIVER":    "memory",
+		"UPLOAD_URL_PREFIX": "https://cdn.example.com/files/",
+	})
+	previous := storage.Default
+	t.Cleanup(func() { storage.Default = previous })
+	storage.InitStorage()
+	require.NoError(t, storage.Default.Put(context.Background(), "a.webp", strings.NewReader("photo"), 5, "image/webp"))
+	require.NoError(t, storage.Default.Put(context.Background(), storage.QuarantinePrefix+"b.webp", strings.NewReader("photo"), 5, "image/webp"))
+
+	router := gin.New()
+	UploadRoutes(router)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/files/a.webp", http.StatusOK},
+		{"/uploads/a.webp", http.StatusNotFound},
+		{"/files/missing.webp", http.StatusNotFound},
+		{"/files/" + storage.QuarantinePrefix + "b.webp", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		assert.Equal(t, tt.status, w.Code, tt.path)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/a.webp", nil))
+	assert.Equal(t, "photo", w.Body.String())
+	assert.Equal(t, "image/webp", w.Header().Get("Content-Type"))
+}
+
+// unverifiedStorage hides the signature verification of the storage it wraps, like backends
+// that hand out URLs of their own
+type unverifiedStorage struct {
+	storage.Storage
+}
+
+func TestUploadRoutesVerifySignedURLs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testutil.LoadConfig(t, map[string]string{
+		"STORAGE_DRIVER":      "memory",
+		"STORAGE_SIGNED_URLS": "true",
+		"STORAGE_SIGNING_KEY": "signing-key",
+	})
+	previous := storage.Default
+	t.Cleanup(func() {
+		storage.Default = previous
+		storage.SignedURLs = false
+	})
+	storage.InitStorage()
+	require.NoError(t, storage.Default.Put(context.Background(), "a.webp", strings.NewReader("photo"), 5, "image/webp"))
+
+	router := gin.New()
+	UploadRoutes(router)
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	signed := storage.PresentURL(context.Background(), storage.Default.URL("a.webp"))
+	require.Contains(t, signed, "signature=")
+	assert.Equal(t, http.StatusOK, get(signed))
+	assert.Equal(t, http.StatusForbidden, get(storage.Default.URL("a.webp")), "unsigned")
+	assert.Equal(t, http.StatusForbidden, get(strings.Replace(signed, "signature=", "signature=0", 1)), "tampered")
+
+	// Backends that cannot verify the signature refuse every request
+	storage.Default = unverifiedStorage{storage.Default}
+	assert.Equal(t, http.StatusForbidden, get(signed))
+}
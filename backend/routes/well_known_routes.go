@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/user/tower-tracker-bima/backend/controllers"
+)
+
+func WellKnownRoutes(router *gin.Engine) {
+	// Public keys for services verifying the tokens issued here
+	router.GET("/.well-known/jwks.json", controllers.GetJWKS)
+}
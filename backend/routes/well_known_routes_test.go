@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/user/tower-tracker-bima/backend/helper"
+	"github.com/user/tower-tracker-bima/backend/testutil"
+)
+
+func TestJWKSEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "jwt.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	testutil.LoadConfig(t, map[string]string{"JWT_SIGNING_KEY_FILE": keyFile, "JWT_PREVIOUS_SECRETS": "former-secret"})
+	require.NoError(t, helper.InitJWTKeys())
+
+	router := gin.New()
+	WellKnownRoutes(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &jwks))
+	require.Len(t, jwks.Keys, 1, "only the RSA key is published, never the secrets")
+	assert.Equal(t, map[string]string{
+		"kty": "RSA",
+		"kid": helper.JWTKeys.Current.ID,
+		"alg": "RS256",
+		"use": "sig",
+		"n":   jwks.Keys[0]["n"],
+		"e":   "AQAB",
+	}, jwks.Keys[0])
+	assert.NotEmpty(t, jwks.Keys[0]["n"])
+}
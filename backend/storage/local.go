@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStorage keeps uploads in a directory on the local filesystem
+type LocalStorage struct {
+	Dir        string // Root directory, e.g. "uploads"
+	BaseURL    string // URL prefix the directory is served under, e.g. "/uploads"
+	SigningKey []byte // HMAC key used for signed URLs
+}
+
+// NewLocalStorage creates the upload directory if needed and returns a storage rooted at it
+func NewLocalStorage(dir, baseURL string, signingKey []byte) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory %s: %w", dir, err)
+	}
+	return &LocalStorage{Dir: dir, BaseURL: strings.TrimRight(baseURL, "/"), SigningKey: signingKey}, nil
+}
+
+// Path returns the filesystem path of key
+func (s *LocalStorage) Path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(key))
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	dstPath := s.Path(key)
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
+		return err
+	}
+
+	dstFile, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstFile, r); err != nil {
+		dstFile.Close()
+		os.Remove(dstPath)
+		return err
+	}
+	return dstFile.Close()
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := os.Open(s.Path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.Path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStorage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := os.Stat(s.Path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *LocalStorage) List(ctx context.Context) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.WalkDir(s.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.Dir, p)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return objects, err
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.BaseURL + "/" + key
+}
+
+// SignedURL appends an expiry timestamp and an HMAC signature, checked by VerifySignature when serving
+func (s *LocalStorage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return signURL(s.SigningKey, s.URL(key), key, expiry)
+}
+
+// VerifySignature checks the expires and signature query parameters of a signed URL
+func (s *LocalStorage) VerifySignature(key, expires, signature string) bool {
+	return verifyURLSignature(s.SigningKey, key, expires, signature)
+}
+
+func (s *LocalStorage) KeyFromURL(url string) (string, bool) {
+	return keyFromPrefixedURL(s.BaseURL, url)
+}
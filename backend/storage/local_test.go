@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseSignedURL splits a signed URL of local storage into its key and query parameters
+func parseSignedURL(t *testing.T, s *LocalStorage, signed string) (string, url.Values) {
+	parsed, err := url.Parse(signed)
+	require.NoError(t, err)
+	key, ok := s.KeyFromURL(parsed.Path)
+	require.True(t, ok, "signed URL %s is under the base URL", signed)
+	return key, parsed.Query()
+}
+
+func TestLocalStorageSignedURL(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir(), "/uploads", []byte("signing-key"))
+	require.NoError(t, err)
+
+	signed, err := s.SignedURL(context.Background(), "photos/a.webp", time.Minute)
+	require.NoError(t, err)
+	key, query := parseSignedURL(t, s, signed)
+	assert.Equal(t, "photos/a.webp", key)
+	expires, signature := query.Get("expires"), query.Get("signature")
+	assert.True(t, s.VerifySignature(key, expires, signature))
+
+	// The signature covers the key and the expiry
+	assert.False(t, s.VerifySignature("photos/b.webp", expires, signature))
+	later := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	assert.False(t, s.VerifySignature(key, later, signature))
+	assert.False(t, s.VerifySignature(key, expires, ""))
+	assert.False(t, s.VerifySignature(key, "soon", signature))
+
+	// Signatures of another key do not verify
+	other, err := NewLocalStorage(t.TempDir(), "/uploads", []byte("other-key"))
+	require.NoError(t, err)
+	assert.False(t, other.VerifySignature(key, expires, signature))
+}
+
+func TestLocalStorageSignedURLExpires(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir(), "/uploads", []byte("signing-key"))
+	require.NoError(t, err)
+
+	signed, err := s.SignedURL(context.Background(), "a.webp", -time.Minute)
+	require.NoError(t, err)
+	key, query := parseSignedURL(t, s, signed)
+	assert.False(t, s.VerifySignature(key, query.Get("expires"), query.Get("signature")))
+}
+
+func TestLocalStorageSignedURLNeedsKey(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir(), "/uploads", nil)
+	require.NoError(t, err)
+
+	_, err = s.SignedURL(context.Background(), "a.webp", time.Minute)
+	assert.Error(t, err)
+}
+
+func TestPresentURL(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir(), "/uploads", []byte("signing-key"))
+	require.NoError(t, err)
+	previous, previousSigned := Default, SignedURLs
+	t.Cleanup(func() { Default, SignedURLs = previous, previousSigned })
+	Default = s
+
+	SignedURLs = false
+	assert.Equal(t, "/uploads/a.webp", PresentURL(context.Background(), "/uploads/a.webp"))
+
+	SignedURLs = true
+	presented := PresentURL(context.Background(), "/uploads/a.webp")
+	key, query := parseSignedURL(t, s, presented)
+	assert.True(t, s.VerifySignature(key, query.Get("expires"), query.Get("signature")))
+	assert.Equal(t, "https://example.com/a.jpg", PresentURL(context.Background(), "https://example.com/a.jpg"), "foreign URLs are left alone")
+}
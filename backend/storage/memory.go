@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+// MemoryStorage keeps uploads in memory. It is meant for tests and local experiments,
+// as everything is lost when the process exits.
+type MemoryStorage struct {
+	BaseURL    string
+	SigningKey []byte // HMAC key used for signed URLs
+
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// NewMemoryStorage returns an empty in-memory storage serving URLs under baseURL
+func NewMemoryStorage(baseURL string) *MemoryStorage {
+	return &MemoryStorage{BaseURL: strings.TrimRight(baseURL, "/"), objects: make(map[string]memoryObject)}
+}
+
+func (s *MemoryStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = memoryObject{data: data, contentType: contentType, modTime: time.Now()}
+	return nil
+}
+
+func (s *MemoryStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	object, ok := s.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(object.data)), nil
+}
+
+func (s *MemoryStorage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *MemoryStorage) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.objects[key]
+	return ok, nil
+}
+
+func (s *MemoryStorage) List(ctx context.Context) ([]ObjectInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	objects := make([]ObjectInfo, 0, len(s.objects))
+	for key, object := range s.objects {
+		objects = append(objects, ObjectInfo{Key: key, Size: int64(len(object.data)), ModTime: object.modTime})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *MemoryStorage) URL(key string) string {
+	return s.BaseURL + "/" + key
+}
+
+// SignedURL appends an expiry timestamp and an HMAC signature, checked by VerifySignature when serving
+func (s *MemoryStorage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return signURL(s.SigningKey, s.URL(key), key, expiry)
+}
+
+// VerifySignature checks the expires and signature query parameters of a signed URL
+func (s *MemoryStorage) VerifySignature(key, expires, signature string) bool {
+	return verifyURLSignature(s.SigningKey, key, expires, signature)
+}
+
+func (s *MemoryStorage) KeyFromURL(url string) (string, bool) {
+	return keyFromPrefixedURL(s.BaseURL, url)
+}
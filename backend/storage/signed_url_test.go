@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStorageSignedURL(t *testing.T) {
+	s := NewMemoryStorage("/uploads")
+	_, err := s.SignedURL(context.Background(), "a.webp", time.Minute)
+	assert.Error(t, err, "signing needs a key")
+
+	s.SigningKey = []byte("signing-key")
+	signed, err := s.SignedURL(context.Background(), "a.webp", time.Minute)
+	require.NoError(t, err)
+	parsed, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "/uploads/a.webp", parsed.Path)
+	query := parsed.Query()
+	assert.True(t, s.VerifySignature("a.webp", query.Get("expires"), query.Get("signature")))
+	assert.False(t, s.VerifySignature("b.webp", query.Get("expires"), query.Get("signature")))
+	assert.False(t, s.VerifySignature("a.webp", query.Get("expires"), ""))
+}
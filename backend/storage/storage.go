@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/user/tower-tracker-bima/backend/config"
+)
+
+// ErrNotFound is returned when no object is stored under the requested key
+var ErrNotFound = errors.New("storage: object not found")
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Storage abstracts where uploaded files (tower photos and their renditions) are kept.
+// Keys are slash separated paths relative to the storage root, e.g. "3af9...webp".
+type Storage interface {
+	// Put stores the contents of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key, returning ErrNotFound if it does not exist
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// Exists reports whether an object is stored under key
+	Exists(ctx context.Context, key string) (bool, error)
+	// List returns every stored object
+	List(ctx context.Context) ([]ObjectInfo, error)
+	// URL returns the public URL of the object stored under key
+	URL(key string) string
+	// SignedURL returns a URL for the object stored under key that stops working after expiry
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// KeyFromURL maps a URL previously returned by URL back to its key
+	KeyFromURL(url string) (string, bool)
+}
+
+// Default is the storage backend used for uploads, set up by InitStorage
+var Default Storage
+
+// SignedURLs enables handing out expiring signed URLs instead of public ones
+var SignedURLs bool
+
+// SignedURLTTL is how long signed URLs stay valid
+var SignedURLTTL = 15 * time.Minute
+
+// InitStorage sets up Default from the storage section of config.Current
+func InitStorage() {
+	cfg := config.Current.Storage
+	SignedURLs = cfg.SignedURLs
+	SignedURLTTL = cfg.SignedURLTTL
+
+	var err error
+	switch cfg.Driver {
+	case "local":
+		Default, err = NewLocalStorage(cfg.UploadDir, cfg.URLPrefix, []byte(cfg.SigningKey))
+	case "s3":
+		Default, err = NewS3Storage(S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			UseSSL:    cfg.S3.UseSSL,
+			PublicURL: cfg.S3.PublicURL,
+		})
+	case "memory":
+		memory := NewMemoryStorage(cfg.URLPrefix)
+		memory.SigningKey = []byte(cfg.SigningKey)
+		Default = memory
+	default:
+		log.Fatalf("Unknown STORAGE_DRIVER %q", cfg.Driver)
+	}
+	if err != nil {
+		log.Fatalf("Failed to initialize %s storage: %v", cfg.Driver, err)
+	}
+
+	log.Printf("Upload storage initialized using the %s driver.", cfg.Driver)
+}
+
+// PresentURL returns the URL clients should use for a stored file URL. When signed URLs are
+// enabled it is replaced by a freshly signed one; URLs not managed by Default are returned unchanged.
+func PresentURL(ctx context.Context, url string) string {
+	if !SignedURLs || url == "" || Default == nil {
+		return url
+	}
+	key, ok := Default.KeyFromURL(url)
+	if !ok {
+		return url
+	}
+	signed, err := Default.SignedURL(ctx, key, SignedURLTTL)
+	if err != nil {
+		log.Printf("Failed to sign URL for %s: %v", key, err)
+		return url
+	}
+	return signed
+}
+
+// CleanKey normalizes a key and rejects keys that would escape the storage root
+func CleanKey(key string) (string, bool) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if cleaned == "" || cleaned != strings.TrimPrefix(key, "/") {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// keyFromPrefixedURL strips prefix (a base URL without trailing slash) from url
+func keyFromPrefixedURL(prefix, url string) (string, bool) {
+	key, found := strings.CutPrefix(url, prefix+"/")
+	if !found {
+		return "", false
+	}
+	return CleanKey(key)
+}
+
+// QuarantinePrefix is the key prefix orphaned uploads are moved under before they are deleted
+const QuarantinePrefix = "quarantine/"
+
+// Move copies the object stored under from to the key to and then deletes the original
+func Move(ctx context.Context, s Storage, from, to string) error {
+	reader, err := s.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := s.Put(ctx, to, bytes.NewReader(data), int64(len(data)), mime.TypeByExtension(path.Ext(from))); err != nil {
+		return err
+	}
+	return s.Delete(ctx, from)
+}
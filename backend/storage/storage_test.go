@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStorage checks the behaviour every Storage must share. Keys are created below prefix, so
+// that backends with existing objects can be tested.
+func testStorage(t *testing.T, s Storage, prefix string) {
+	ctx := context.Background()
+	key := prefix + "photos/a.webp"
+	put := func(key, content string) {
+		t.Helper()
+		require.NoError(t, s.Put(ctx, key, bytes.NewReader([]byte(content)), int64(len(content)), "image/webp"))
+	}
+	get := func(key string) string {
+		t.Helper()
+		reader, err := s.Get(ctx, key)
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	_, err := s.Get(ctx, key)
+	assert.True(t, errors.Is(err, ErrNotFound), "Get of a missing object: %v", err)
+	exists, err := s.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.NoError(t, s.Delete(ctx, key), "deleting a missing object is not an error")
+
+	put(key, "first")
+	put(key, "second")
+	exists, err = s.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "second", get(key), "Put replaces existing objects")
+
+	objects, err := s.List(ctx)
+	require.NoError(t, err)
+	var listed *ObjectInfo
+	for i := range objects {
+		if objects[i].Key == key {
+			listed = &objects[i]
+		}
+	}
+	require.NotNil(t, listed, "List includes %s", key)
+	assert.EqualValues(t, len("second"), listed.Size)
+	assert.False(t, listed.ModTime.IsZero())
+
+	// URLs map back to their keys, other URLs do not
+	fromURL, ok := s.KeyFromURL(s.URL(key))
+	assert.True(t, ok)
+	assert.Equal(t, key, fromURL)
+	_, ok = s.KeyFromURL("https://elsewhere.example.com/" + key)
+	assert.False(t, ok)
+	_, ok = s.KeyFromURL(s.URL("../secret"))
+	assert.False(t, ok)
+
+	quarantined := QuarantinePrefix + key
+	require.NoError(t, Move(ctx, s, key, quarantined))
+	exists, err = s.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, exists, "Move removes the original")
+	assert.Equal(t, "second", get(quarantined))
+
+	require.NoError(t, s.Delete(ctx, quarantined))
+	exists, err = s.Exists(ctx, quarantined)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestLocalStorage(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir(), "/uploads/", []byte("key"))
+	require.NoError(t, err)
+	testStorage(t, s, "")
+}
+
+func TestMemoryStorage(t *testing.T) {
+	testStorage(t, NewMemoryStorage("/uploads"), "")
+}
+
+// TestS3Storage runs against an S3-compatible object store when TEST_S3_ENDPOINT is set, e.g. MinIO:
+//
+//	docker run -d -p 9000:9000 minio/minio server /data
+//	mc alias set local http://localhost:9000 minioadmin minioadmin && mc mb local/test
+//	TEST_S3_ENDPOINT=localhost:9000 TEST_S3_BUCKET=test TEST_S3_ACCESS_KEY=minioadmin TEST_S3_SECRET_KEY=minioadmin go test ./backend/storage
+func TestS3Storage(t *testing.T) {
+	endpoint := os.Getenv("TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("TEST_S3_ENDPOINT is not set")
+	}
+	s, err := NewS3Storage(S3Config{
+		Endpoint:  endpoint,
+		Bucket:    os.Getenv("TEST_S3_BUCKET"),
+		AccessKey: os.Getenv("TEST_S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("TEST_S3_SECRET_KEY"),
+		UseSSL:    os.Getenv("TEST_S3_USE_SSL") == "true",
+	})
+	require.NoError(t, err)
+
+	suffix := make([]byte, 6)
+	_, err = rand.Read(suffix)
+	require.NoError(t, err)
+	testStorage(t, s, "test-"+hex.EncodeToString(suffix)+"/")
+}
+
+func TestCleanKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"a.webp", "a.webp", true},
+		{"/a.webp", "a.webp", true},
+		{"photos/a.webp", "photos/a.webp", true},
+		{"", "", false},
+		{"/", "", false},
+		{"../a.webp", "", false},
+		{"photos/../../a.webp", "", false},
+		{"photos//a.webp", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := CleanKey(tt.key)
+		assert.Equal(t, tt.ok, ok, "CleanKey(%q)", tt.key)
+		assert.Equal(t, tt.want, got, "CleanKey(%q)", tt.key)
+	}
+}
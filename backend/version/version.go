@@ -0,0 +1,45 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build information, injected at build time with
+//
+//	go build -ldflags "-X github.com/user/tower-tracker-bima/backend/version.Commit=$(git rev-parse HEAD) -X github.com/user/tower-tracker-bima/backend/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information. Values not injected through ldflags fall back to the VCS
+// information the Go toolchain stamps into module builds, and to "unknown".
+func Get() Info {
+	info := Info{Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
@@ -1,4 +1,5 @@
 import axios from 'axios';
+import ToastEventBus from 'primevue/toasteventbus';
 import { useAuthStore } from '../stores/auth.js';
 
 const apiClient = axios.create({
@@ -14,4 +15,21 @@ apiClient.interceptors.request.use(config => {
   return config;
 });
 
+// Headers making a write conditional on the resource still being at the version it was fetched with
+export const ifMatch = (version) => ({ 'If-Match': `"${version}"` });
+
+// True when a conditional write failed because someone else changed the resource in the meantime
+export const isVersionConflict = (error) => error.response?.status === 412;
+
+// Handles a version conflict by telling the user and calling reload to refresh the stale data.
+// Returns false for other errors, which are left to the caller.
+export const handleVersionConflict = (error, reload) => {
+  if (!isVersionConflict(error)) {
+    return false;
+  }
+  ToastEventBus.emit('add', { severity: 'warn', summary: 'Changed Elsewhere', detail: 'This was changed by someone else in the meantime. The list was reloaded, please review and try again.', life: 5000 });
+  reload();
+  return true;
+};
+
 export default apiClient;
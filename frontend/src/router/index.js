@@ -3,6 +3,7 @@ import { useAuthStore } from '../stores/auth.js';
 
 const Towers = () => import('../views/Towers.vue');
 const Login = () => import('../views/Login.vue');
+const LoginCallback = () => import('../views/LoginCallback.vue');
 const Providers = () => import('../views/Providers.vue');
 const Admin = () => import('../views/Admin.vue');
 const AdminTowers = () => import('../views/AdminTowers.vue');
@@ -27,6 +28,11 @@ const routes = [
     name: 'Login',
     component: Login
   },
+  {
+    path: '/login/callback',
+    name: 'LoginCallback',
+    component: LoginCallback
+  },
   {
     path: '/admin',
     name: 'Admin',
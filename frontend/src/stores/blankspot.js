@@ -1,5 +1,5 @@
 import { defineStore } from 'pinia';
-import apiClient from '../api/axios';
+import apiClient, { ifMatch } from '../api/axios';
 
 export const useBlankspotStore = defineStore('blankspot', {
   state: () => ({
@@ -25,9 +25,9 @@ export const useBlankspotStore = defineStore('blankspot', {
         throw error;
       }
     },
-    async updateBlankspotArea(id, blankspotData) {
+    async updateBlankspotArea(id, version, blankspotData) {
       try {
-        const response = await apiClient.put(`/blankspots/${id}`, blankspotData);
+        const response = await apiClient.put(`/blankspots/${id}`, blankspotData, { headers: ifMatch(version) });
         const index = this.blankspotAreas.findIndex(area => area.ID === id);
         if (index !== -1) {
           this.blankspotAreas[index] = response.data.data;
@@ -1,5 +1,5 @@
 import { defineStore } from 'pinia';
-import apiClient from '../api/axios';
+import apiClient, { ifMatch } from '../api/axios';
 
 export const useDataStore = defineStore('data', {
   state: () => ({
@@ -30,11 +30,12 @@ export const useDataStore = defineStore('data', {
         throw error;
       }
     },
-    async updateTower(id, towerData) {
+    async updateTower(id, version, towerData) {
       try {
         const response = await apiClient.put(`/towers/${id}`, towerData, {
           headers: {
-            'Content-Type': 'multipart/form-data'
+            'Content-Type': 'multipart/form-data',
+            ...ifMatch(version)
           }
         });
         // State is updated by calling fetchTowers() in the component
@@ -44,9 +45,9 @@ export const useDataStore = defineStore('data', {
         throw error;
       }
     },
-    async deleteTower(id) {
+    async deleteTower(id, version) {
       try {
-        await apiClient.delete(`/towers/${id}`);
+        await apiClient.delete(`/towers/${id}`, { headers: ifMatch(version) });
         // State is updated by calling fetchTowers() in the component
       } catch (error) {
         console.error('Error deleting tower:', error);
@@ -73,9 +74,9 @@ export const useDataStore = defineStore('data', {
         throw error;
       }
     },
-    async updateProvider(id, providerData) {
+    async updateProvider(id, version, providerData) {
       try {
-        const response = await apiClient.put(`/providers/${id}`, providerData);
+        const response = await apiClient.put(`/providers/${id}`, providerData, { headers: ifMatch(version) });
         // For simplicity, we can refetch providers in the component as well
         return response.data;
       } catch (error) {
@@ -93,9 +94,9 @@ export const useDataStore = defineStore('data', {
       }
     },
 
-    async permanentDeleteTower(towerId) {
+    async permanentDeleteTower(towerId, version) {
       try {
-        await apiClient.delete(`/towers/${towerId}`);
+        await apiClient.delete(`/towers/${towerId}`, { headers: ifMatch(version) });
         // State is updated by calling fetchTowers() in the component
       } catch (error) {
         console.error('Error permanently deleting tower:', error);
@@ -103,9 +104,9 @@ export const useDataStore = defineStore('data', {
       }
     },
 
-    async changeTowerOwnership(towerId, newProviderId) {
+    async changeTowerOwnership(towerId, version, newProviderId) {
       try {
-        const response = await apiClient.put(`/towers/${towerId}/ownership`, { new_provider_id: newProviderId });
+        const response = await apiClient.put(`/towers/${towerId}/ownership`, { new_provider_id: newProviderId }, { headers: ifMatch(version) });
         // State is updated by calling fetchTowers() in the component
         return response.data;
       } catch (error) {
@@ -113,9 +114,9 @@ export const useDataStore = defineStore('data', {
         throw error;
       }
     },
-    async relocateTower(towerId, latitude, longitude) {
+    async relocateTower(towerId, version, latitude, longitude) {
       try {
-        const response = await apiClient.put(`/towers/${towerId}/relocate`, { latitude, longitude });
+        const response = await apiClient.put(`/towers/${towerId}/relocate`, { latitude, longitude }, { headers: ifMatch(version) });
         // State is updated by calling fetchTowers() in the component
         return response.data;
       } catch (error) {
@@ -123,9 +124,9 @@ export const useDataStore = defineStore('data', {
         throw error;
       }
     },
-    async dismantleTower(towerId) {
+    async dismantleTower(towerId, version) {
       try {
-        const response = await apiClient.put(`/towers/${towerId}/dismantle`);
+        const response = await apiClient.put(`/towers/${towerId}/dismantle`, null, { headers: ifMatch(version) });
         // State is updated by calling fetchTowers() in the component
         return response.data;
       } catch (error) {